@@ -0,0 +1,481 @@
+(function () {
+    "use strict";
+
+    var METHODS = ["get", "post", "put", "patch", "delete", "head", "options", "trace"];
+    var root = document.getElementById("docs");
+    var layout = root.getAttribute("data-layout");
+    var tryIt = root.getAttribute("data-try-it") === "true";
+    var spec = {};
+
+    // h creates an element, text is always set through text nodes so the
+    // document can not inject markup
+    function h(tag, attrs) {
+        var el = document.createElement(tag);
+        Object.keys(attrs || {}).forEach(function (key) {
+            if (key === "class") {
+                el.className = attrs[key];
+            } else if (key.indexOf("on") === 0) {
+                el.addEventListener(key.substring(2), attrs[key]);
+            } else if (attrs[key] !== undefined && attrs[key] !== null) {
+                el.setAttribute(key, attrs[key]);
+            }
+        });
+
+        for (var index = 2; index < arguments.length; index++) {
+            append(el, arguments[index]);
+        }
+
+        return el;
+    }
+
+    function append(el, child) {
+        if (child === undefined || child === null || child === false) {
+            return;
+        }
+
+        if (Array.isArray(child)) {
+            child.forEach(function (c) {
+                append(el, c);
+            });
+        } else if (child instanceof Node) {
+            el.appendChild(child);
+        } else {
+            el.appendChild(document.createTextNode(String(child)));
+        }
+    }
+
+    function resolve(schema) {
+        var seen = 0;
+        while (schema && schema.$ref && seen++ < 32) {
+            var path = schema.$ref.replace(/^#\//, "").split("/");
+            schema = path.reduce(function (node, key) {
+                return node ? node[key.replace(/~1/g, "/").replace(/~0/g, "~")] : undefined;
+            }, spec);
+        }
+
+        return schema || {};
+    }
+
+    function refName(schema) {
+        return schema && schema.$ref ? schema.$ref.split("/").pop() : "";
+    }
+
+    function merged(schema) {
+        schema = resolve(schema);
+        if (!schema.allOf) {
+            return schema;
+        }
+
+        var result = Object.assign({}, schema);
+        delete result.allOf;
+        schema.allOf.forEach(function (part) {
+            var resolved = merged(part);
+            Object.keys(resolved).forEach(function (key) {
+                if (key === "properties") {
+                    result.properties = Object.assign({}, result.properties, resolved.properties);
+                } else if (key === "required") {
+                    result.required = (result.required || []).concat(resolved.required);
+                } else if (result[key] === undefined) {
+                    result[key] = resolved[key];
+                }
+            });
+        });
+
+        return result;
+    }
+
+    function typeName(schema) {
+        var name = refName(schema);
+        var s = merged(schema);
+        if (s.type === "array") {
+            return "array<" + typeName(s.items || {}) + ">";
+        }
+
+        var type = Array.isArray(s.type) ? s.type.join(" | ") : (s.type || (s.properties ? "object" : "any"));
+        if (s.format) {
+            type += " (" + s.format + ")";
+        }
+
+        return name ? name : type;
+    }
+
+    var CONSTRAINTS = ["minLength", "maxLength", "minimum", "maximum", "minItems", "maxItems", "pattern", "default"];
+
+    function constraints(schema) {
+        var s = merged(schema);
+        var list = CONSTRAINTS.filter(function (key) {
+            return s[key] !== undefined;
+        }).map(function (key) {
+            return key + ": " + JSON.stringify(s[key]);
+        });
+
+        if (s.enum) {
+            list.push("enum: " + s.enum.map(function (v) {
+                return JSON.stringify(v);
+            }).join(", "));
+        }
+
+        return list.length ? h("span", {class: "constraint"}, list.join(", ")) : null;
+    }
+
+    function renderSchema(schema, depth, seen) {
+        seen = seen || [];
+        var s = merged(schema);
+        var name = refName(schema);
+        if (depth > 8 || (name && seen.indexOf(name) >= 0)) {
+            return h("div", {class: "schema muted"}, name ? "recursive " + name : "...");
+        }
+
+        if (name) {
+            seen = seen.concat([name]);
+        }
+
+        if (s.type === "array" && s.items) {
+            return renderSchema(s.items, depth + 1, seen);
+        }
+
+        var variants = s.oneOf || s.anyOf;
+        if (variants) {
+            return h("div", {class: "schema"}, variants.map(function (variant, index) {
+                return h("div", {class: "schema-row"},
+                    h("span", {class: "muted"}, (s.oneOf ? "one of #" : "any of #") + (index + 1)),
+                    h("span", {class: "type"}, typeName(variant)),
+                    renderSchema(variant, depth + 1, seen));
+            }));
+        }
+
+        var properties = s.properties || {};
+        var required = s.required || [];
+        var rows = Object.keys(properties).map(function (key) {
+            var property = properties[key];
+            var resolved = merged(property);
+            var nested = resolved.properties || (resolved.type === "array" && merged(resolved.items || {}).properties) ||
+                resolved.oneOf || resolved.anyOf;
+            return h("div", {class: "schema-row"},
+                h("span", {class: "name"}, key),
+                h("span", {class: "type"}, typeName(property)),
+                required.indexOf(key) >= 0 ? h("span", {class: "required"}, "required") : null,
+                constraints(property),
+                resolved.description ? h("div", {class: "muted"}, resolved.description) : null,
+                nested ? renderSchema(property, depth + 1, seen) : null);
+        });
+
+        if (s.additionalProperties && typeof s.additionalProperties === "object") {
+            rows.push(h("div", {class: "schema-row"},
+                h("span", {class: "name"}, "*"),
+                h("span", {class: "type"}, typeName(s.additionalProperties)),
+                renderSchema(s.additionalProperties, depth + 1, seen)));
+        }
+
+        if (!rows.length) {
+            return depth === 0 ? h("div", {class: "schema"}, h("span", {class: "type"}, typeName(schema)), constraints(schema)) : null;
+        }
+
+        return h("div", {class: "schema"}, rows);
+    }
+
+    function sample(schema, depth, seen) {
+        seen = seen || [];
+        var s = merged(schema);
+        var name = refName(schema);
+        if (depth > 8 || (name && seen.indexOf(name) >= 0)) {
+            return null;
+        }
+
+        if (name) {
+            seen = seen.concat([name]);
+        }
+
+        if (s.example !== undefined) {
+            return s.example;
+        }
+
+        if (s.default !== undefined) {
+            return s.default;
+        }
+
+        if (s.enum) {
+            return s.enum[0];
+        }
+
+        if (s.oneOf || s.anyOf) {
+            return sample((s.oneOf || s.anyOf)[0], depth + 1, seen);
+        }
+
+        var type = Array.isArray(s.type) ? s.type[0] : s.type;
+        if (!type && s.properties) {
+            type = "object";
+        }
+
+        switch (type) {
+            case "object":
+                var result = {};
+                Object.keys(s.properties || {}).forEach(function (key) {
+                    result[key] = sample(s.properties[key], depth + 1, seen);
+                });
+
+                return result;
+            case "array":
+                return [sample(s.items || {}, depth + 1, seen)];
+            case "integer":
+                return s.minimum || 0;
+            case "number":
+                return s.minimum || 0.0;
+            case "boolean":
+                return true;
+            case "string":
+                if (s.format === "date-time") {
+                    return new Date(0).toISOString();
+                }
+
+                return s.format === "binary" ? "" : "string";
+            default:
+                return null;
+        }
+    }
+
+    function jsonContent(content) {
+        if (!content) {
+            return null;
+        }
+
+        var types = Object.keys(content);
+        var type = types.filter(function (t) {
+            return t.indexOf("json") >= 0;
+        })[0] || types[0];
+        return type ? {type: type, media: content[type] || {}} : null;
+    }
+
+    function operations() {
+        var list = [];
+        Object.keys(spec.paths || {}).forEach(function (path) {
+            var item = spec.paths[path] || {};
+            METHODS.forEach(function (method) {
+                if (item[method]) {
+                    list.push({
+                        path: path,
+                        method: method,
+                        op: item[method],
+                        parameters: (item.parameters || []).concat(item[method].parameters || []).map(resolve),
+                        id: "op-" + method + "-" + path.replace(/[^A-Za-z0-9]+/g, "-")
+                    });
+                }
+            });
+        });
+
+        return list;
+    }
+
+    function groupByTag(list) {
+        var groups = {};
+        var order = [];
+        list.forEach(function (o) {
+            (o.op.tags && o.op.tags.length ? o.op.tags : ["default"]).forEach(function (tag) {
+                if (!groups[tag]) {
+                    groups[tag] = [];
+                    order.push(tag);
+                }
+
+                groups[tag].push(o);
+            });
+        });
+
+        return order.map(function (tag) {
+            return {tag: tag, operations: groups[tag]};
+        });
+    }
+
+    function renderParameters(parameters) {
+        if (!parameters.length) {
+            return null;
+        }
+
+        return [h("h3", {}, "Parameters"), h("table", {},
+            h("tr", {}, h("th", {}, "Name"), h("th", {}, "In"), h("th", {}, "Type"), h("th", {}, "Description")),
+            parameters.map(function (p) {
+                return h("tr", {},
+                    h("td", {}, h("code", {}, p.name), p.required ? h("span", {class: "required"}, " *") : null),
+                    h("td", {}, p.in),
+                    h("td", {}, typeName(p.schema || {}), constraints(p.schema || {})),
+                    h("td", {}, p.description || ""));
+            }))];
+    }
+
+    function renderResponses(responses) {
+        return [h("h3", {}, "Responses"), Object.keys(responses || {}).sort().map(function (code) {
+            var response = resolve(responses[code]);
+            var content = jsonContent(response.content);
+            return h("div", {class: "schema-row"},
+                h("span", {class: "name"}, code),
+                h("span", {class: "muted"}, " " + (response.description || "")),
+                content ? h("span", {class: "type"}, content.type) : null,
+                content && content.media.schema ? renderSchema(content.media.schema, 0) : null);
+        })];
+    }
+
+    function renderSamples(o) {
+        var parts = [];
+        var body = o.op.requestBody && jsonContent(resolve(o.op.requestBody).content);
+        if (body && body.media.schema) {
+            parts.push(h("h3", {}, "Request sample"), h("pre", {}, JSON.stringify(sample(body.media.schema, 0), null, 2)));
+        }
+
+        Object.keys(o.op.responses || {}).sort().forEach(function (code) {
+            var content = jsonContent(resolve(o.op.responses[code]).content);
+            if (content && content.media.schema) {
+                parts.push(h("h3", {}, "Response " + code), h("pre", {}, JSON.stringify(sample(content.media.schema, 0), null, 2)));
+            }
+        });
+
+        return h("div", {class: "samples"}, parts.length ? h("div", {class: "panel"}, parts) : null);
+    }
+
+    function baseUrl() {
+        var server = spec.servers && spec.servers[0] && spec.servers[0].url;
+        return server ? server.replace(/\/$/, "") : "";
+    }
+
+    function renderTryIt(o) {
+        var inputs = {};
+        var body = o.op.requestBody && jsonContent(resolve(o.op.requestBody).content);
+        var bodyInput = body ? h("textarea", {}, JSON.stringify(sample(body.media.schema || {}, 0), null, 2)) : null;
+        var output = h("pre", {class: "response"}, "");
+        output.style.display = "none";
+
+        function send() {
+            var url = o.path;
+            var query = [];
+            var headers = {};
+            o.parameters.forEach(function (p) {
+                var value = inputs[p.in + ":" + p.name].value;
+                if (value === "") {
+                    return;
+                }
+
+                if (p.in === "path") {
+                    url = url.replace("{" + p.name + "}", encodeURIComponent(value));
+                } else if (p.in === "query") {
+                    query.push(encodeURIComponent(p.name) + "=" + encodeURIComponent(value));
+                } else if (p.in === "header") {
+                    headers[p.name] = value;
+                }
+            });
+
+            var init = {method: o.method.toUpperCase(), headers: headers};
+            if (bodyInput) {
+                headers["Content-Type"] = body.type;
+                init.body = bodyInput.value;
+            }
+
+            output.style.display = "block";
+            output.textContent = "...";
+            fetch(baseUrl() + url + (query.length ? "?" + query.join("&") : ""), init).then(function (response) {
+                return response.text().then(function (text) {
+                    try {
+                        text = JSON.stringify(JSON.parse(text), null, 2);
+                    } catch (ignored) {
+                    }
+
+                    output.textContent = response.status + " " + response.statusText + "\n\n" + text;
+                });
+            }).catch(function (err) {
+                output.textContent = String(err);
+            });
+        }
+
+        return h("div", {class: "try-it"},
+            o.parameters.filter(function (p) {
+                return p.in !== "cookie";
+            }).map(function (p) {
+                var input = h("input", {placeholder: typeName(p.schema || {})});
+                inputs[p.in + ":" + p.name] = input;
+                return [h("label", {}, p.name + " (" + p.in + ")"), input];
+            }),
+            bodyInput ? [h("label", {}, "body (" + body.type + ")"), bodyInput] : null,
+            h("button", {onclick: send}, "Send"),
+            output);
+    }
+
+    function renderOperation(o) {
+        var op = o.op;
+        var body = op.requestBody && resolve(op.requestBody);
+        var bodyContent = body && jsonContent(body.content);
+        var el = h("div", {class: "operation" + (op.deprecated ? " deprecated" : ""), id: o.id},
+            h("div", {
+                    class: "operation-header", onclick: function () {
+                        el.classList.toggle("open");
+                    }
+                },
+                h("span", {class: "method " + o.method}, o.method),
+                h("span", {class: "path"}, o.path),
+                h("span", {class: "summary"}, op.summary || "")),
+            h("div", {class: "operation-body"},
+                h("div", {class: "operation-columns"},
+                    h("div", {},
+                        op.description ? h("p", {}, op.description) : null,
+                        op.security && op.security.length ? h("p", {class: "muted"}, "Authorization: " + op.security.map(function (s) {
+                            return Object.keys(s).join(" + ");
+                        }).join(" or ")) : null,
+                        renderParameters(o.parameters),
+                        bodyContent ? [h("h3", {}, "Request body ", h("span", {class: "type"}, bodyContent.type)),
+                            bodyContent.media.schema ? renderSchema(bodyContent.media.schema, 0) : null] : null,
+                        renderResponses(op.responses),
+                        tryIt && layout !== "three-column" ? renderTryIt(o) : null),
+                    renderSamples(o))));
+        return el;
+    }
+
+    function render() {
+        var info = spec.info || {};
+        var groups = groupByTag(operations());
+        var filter = h("input", {placeholder: "Filter"});
+        var links = [];
+        var nav = h("div", {class: "nav"}, filter, groups.map(function (group) {
+            return [h("div", {class: "nav-tag"}, group.tag), group.operations.map(function (o) {
+                var link = h("a", {href: "#" + o.id}, h("span", {class: "method " + o.method}, o.method), o.op.summary || o.path);
+                links.push({el: link, text: (o.method + " " + o.path + " " + (o.op.summary || "")).toLowerCase()});
+                return link;
+            })];
+        }));
+
+        filter.addEventListener("input", function () {
+            var term = filter.value.toLowerCase();
+            links.forEach(function (link) {
+                link.el.style.display = link.text.indexOf(term) >= 0 ? "" : "none";
+            });
+        });
+
+        var main = h("div", {class: "main"},
+            h("div", {class: "info"},
+                h("h1", {}, info.title || "API", " ", h("span", {class: "muted"}, info.version || "")),
+                info.description ? h("p", {}, info.description) : null,
+                spec.servers && spec.servers.length ? h("p", {class: "muted"}, "Server: " + baseUrl()) : null),
+            groups.map(function (group) {
+                return [h("h2", {}, group.tag), group.operations.map(renderOperation)];
+            }));
+
+        root.textContent = "";
+        root.appendChild(h("div", {class: "docs"}, nav, main));
+        if (location.hash) {
+            var target = document.getElementById(location.hash.substring(1));
+            if (target) {
+                target.classList.add("open");
+                target.scrollIntoView();
+            }
+        }
+    }
+
+    root.textContent = "Loading...";
+    fetch(root.getAttribute("data-spec-url"), {headers: {Accept: "application/json"}}).then(function (response) {
+        if (!response.ok) {
+            throw new Error("could not load the document: " + response.status);
+        }
+
+        return response.json();
+    }).then(function (doc) {
+        spec = doc;
+        render();
+    }).catch(function (err) {
+        root.textContent = String(err);
+    });
+})();
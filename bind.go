@@ -0,0 +1,217 @@
+package flex
+
+import (
+	"encoding"
+	. "github.com/amirdlt/flex/util"
+	"reflect"
+	"strings"
+)
+
+var bindingSources = []string{"path", "query", "header", "cookie"}
+
+// BindingError is a parameter of the request which could not be bound.
+type BindingError struct {
+	In      string `json:"in"`
+	Name    string `json:"name"`
+	Message string `json:"error"`
+}
+
+type BindingErrors []BindingError
+
+func (e BindingErrors) Error() string {
+	messages := make([]string, len(e))
+	for index, err := range e {
+		messages[index] = err.In + " parameter " + err.Name + ": " + err.Message
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// bindingTag is a parsed tag like query:"limit,required,default=20", the
+// default value is the rest of the tag so it may contain commas.
+type bindingTag struct {
+	in           string
+	name         string
+	required     bool
+	defaultValue *string
+}
+
+func parseBindingTag(field reflect.StructField) (bindingTag, bool) {
+	for _, in := range bindingSources {
+		value, ok := field.Tag.Lookup(in)
+		if !ok {
+			continue
+		}
+
+		tag := bindingTag{in: in}
+		tag.name, value, _ = strings.Cut(value, ",")
+		if tag.name == "-" {
+			return bindingTag{}, false
+		}
+
+		if tag.name == "" {
+			tag.name = field.Name
+		}
+
+		for value != "" {
+			if defaultValue, ok := strings.CutPrefix(value, "default="); ok {
+				tag.defaultValue = &defaultValue
+				break
+			}
+
+			var option string
+			option, value, _ = strings.Cut(value, ",")
+			switch option {
+			case "required":
+				tag.required = true
+			default:
+				panic("unknown option of " + in + " tag of field " + field.Name + ": " + option)
+			}
+		}
+
+		return tag, true
+	}
+
+	return bindingTag{}, false
+}
+
+// BindParams fills the fields of dst, a pointer to a struct, from the path,
+// query, header and cookie parameters named by their tags, e.g.
+//
+//	type params struct {
+//		UserId string        `path:"userId"`
+//		Limit  int           `query:"limit,default=20"`
+//		Ids    []int         `query:"id"`
+//		Tenant string        `header:"X-Tenant,required"`
+//		Since  time.Duration `cookie:"since"`
+//	}
+//
+// Values are converted as by util.SetFromString. All the failures are returned
+// together as BindingErrors.
+func (s *BasicInjector) BindParams(dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		panic("params can only be bound to a non-nil pointer to a struct, got " + reflect.TypeOf(dst).String())
+	}
+
+	var errs BindingErrors
+	s.bindParams(v.Elem(), &errs)
+	if len(errs) != 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// MustBindParams is like BindParams but panics with 400 listing the failures.
+func (s *BasicInjector) MustBindParams(dst any) {
+	if err := s.BindParams(dst); err != nil {
+		panic(s.WrapBadRequestErr([]BindingError(err.(BindingErrors))))
+	}
+}
+
+func (s *BasicInjector) bindParams(v reflect.Value, errs *BindingErrors) {
+	for index := 0; index < v.NumField(); index++ {
+		field := v.Type().Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, ok := parseBindingTag(field)
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				s.bindParams(v.Field(index), errs)
+			}
+
+			continue
+		}
+
+		raws := s.paramValues(tag)
+		if len(raws) == 0 {
+			if tag.required {
+				*errs = append(*errs, BindingError{tag.in, tag.name, "is required"})
+				continue
+			}
+
+			if tag.defaultValue == nil {
+				continue
+			}
+
+			raws = []string{*tag.defaultValue}
+		}
+
+		if err := bindValue(v.Field(index), raws); err != nil {
+			*errs = append(*errs, BindingError{tag.in, tag.name, err.Error()})
+		}
+	}
+}
+
+func (s *BasicInjector) paramValues(tag bindingTag) []string {
+	switch tag.in {
+	case "path":
+		for _, param := range s.pathParameters {
+			if param.Key == tag.name {
+				return []string{param.Value}
+			}
+		}
+	case "query":
+		return s.URL().Query()[tag.name]
+	case "header":
+		return s.r.Header.Values(tag.name)
+	case "cookie":
+		if cookie, err := s.r.Cookie(tag.name); err == nil {
+			return []string{cookie.Value}
+		}
+	}
+
+	return nil
+}
+
+// bindValue stores raws in v, a single raw of a slice is comma separated,
+// e.g. ?id=1,2 as well as ?id=1&id=2.
+func bindValue(v reflect.Value, raws []string) error {
+	textUnmarshaler := reflect.PointerTo(v.Type()).Implements(reflect.TypeFor[encoding.TextUnmarshaler]())
+	if len(raws) == 1 || v.Kind() != reflect.Slice || textUnmarshaler {
+		return SetFromString(v, raws[0])
+	}
+
+	return SetFromStrings(v, raws)
+}
+
+// WithParams documents the parameters of the route from the tags of params,
+// an instance of the struct given to BindParams.
+func (r *RouteInfo) WithParams(params any) *RouteInfo {
+	t := reflect.TypeOf(params)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		panic("params must be a struct, got " + t.String())
+	}
+
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, ok := parseBindingTag(field)
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				r.WithParams(reflect.New(field.Type).Elem().Interface())
+			}
+
+			continue
+		}
+
+		r.WithParameter(RouteParameter{
+			In:       tag.in,
+			Name:     tag.name,
+			Required: tag.required || tag.in == "path",
+			Type:     field.Type,
+		})
+	}
+
+	return r
+}
@@ -0,0 +1,176 @@
+package flex
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DecodeOptions make the decoding of request bodies strict. They are honoured
+// by the codecs implementing OptionsDecoder, e.g. json.
+type DecodeOptions struct {
+	// DisallowUnknownFields rejects the fields which are not in the body type.
+	DisallowUnknownFields bool
+	// UseNumber decodes the numbers of untyped values as json.Number instead of
+	// float64.
+	UseNumber bool
+	// DisallowTrailingData rejects any data after the first value of the body.
+	DisallowTrailingData bool
+}
+
+// OptionsDecoder is implemented by the codecs which support DecodeOptions.
+type OptionsDecoder interface {
+	DecodeWithOptions(r io.Reader, v any, options DecodeOptions) error
+}
+
+// BodyOptions configure the reading of the request bodies by RequestBody.
+type BodyOptions struct {
+	DecodeOptions
+	// MaxSize is the max size of the body in bytes, larger bodies are rejected
+	// with 413. Zero or a negative one means no limit.
+	MaxSize int64
+	// ContentTypes are the accepted media types of the body, e.g.
+	// application/json or image/*, others are rejected with 415. Empty means
+	// any media type supported by the codecs.
+	ContentTypes []string
+}
+
+// SetBodyOptions sets the body options of s and its groups which do not set
+// their own, see RouteInfo.WithBodyOptions to set them for a single route.
+func (s *Server[I]) SetBodyOptions(options BodyOptions) *Server[I] {
+	s.bodyOptions = &options
+	return s
+}
+
+func (s *Server[I]) lookupBodyOptions() BodyOptions {
+	for ; s != nil; s = s.parent {
+		if s.bodyOptions != nil {
+			return *s.bodyOptions
+		}
+	}
+
+	return BodyOptions{}
+}
+
+// WithBodyOptions sets the body options of the route, over the ones of its
+// server.
+func (r *RouteInfo) WithBodyOptions(options BodyOptions) *RouteInfo {
+	r.BodyOptions = &options
+	return r
+}
+
+func (s *BasicInjector) bodyOptions() BodyOptions {
+	if s.route != nil && s.route.BodyOptions != nil {
+		return *s.route.BodyOptions
+	}
+
+	return s.serverBodyOptions
+}
+
+// limitBody checks the content type and size of the body against options
+// and limits the reading of the body to the max size.
+func (s *BasicInjector) limitBody(options BodyOptions) {
+	if len(options.ContentTypes) != 0 {
+		contentType := s.GetRequestHeader("Content-Type")
+		if contentType == "" {
+			panic(s.WrapUnsupportedMediaTypeErr("content type is required, expected one of " + strings.Join(options.ContentTypes, ", ")))
+		}
+
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			panic(s.WrapUnsupportedMediaTypeErr("invalid content type: " + contentType))
+		}
+
+		accepted := slices.ContainsFunc(options.ContentTypes, func(accepted string) bool {
+			if prefix, ok := strings.CutSuffix(accepted, "/*"); ok {
+				return strings.HasPrefix(mediaType, prefix+"/")
+			}
+
+			return strings.EqualFold(mediaType, accepted)
+		})
+
+		if !accepted {
+			panic(s.WrapUnsupportedMediaTypeErr("unsupported content type: " + mediaType + ", expected one of " + strings.Join(options.ContentTypes, ", ")))
+		}
+	}
+
+	maxSize := options.MaxSize
+	if maxSize <= 0 {
+		return
+	}
+
+	if s.r.ContentLength > maxSize {
+		panic(s.WrapRequestEntityTooLargeErr("body is larger than " + strconv.FormatInt(maxSize, 10) + " bytes"))
+	}
+
+	s.r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(s.w, s.r.Body, maxSize)}
+}
+
+// limitedBody records the error of a body larger than the max size, as some
+// decoders, e.g. the one of goccy/go-json, replace it by their own.
+type limitedBody struct {
+	io.ReadCloser
+	err *http.MaxBytesError
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+		b.err = maxBytesErr
+	}
+
+	return n, err
+}
+
+// bodyReadErr converts an error of reading the body to a result, 413 if the
+// body is too large.
+func (s *BasicInjector) bodyReadErr(message string, err error) Result {
+	if body, ok := s.r.Body.(*limitedBody); ok && body.err != nil {
+		err = body.err
+	}
+
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+		return s.WrapRequestEntityTooLargeErr("body is larger than " + strconv.FormatInt(maxBytesErr.Limit, 10) + " bytes")
+	}
+
+	return s.WrapBadRequestErr(message + ", err=" + err.Error())
+}
+
+func (s *BasicInjector) WrapRequestEntityTooLargeErr(err any) Result {
+	return s.WrapJsonErr(err, s.defaultErrorCodes[http.StatusRequestEntityTooLarge], http.StatusRequestEntityTooLarge)
+}
+
+// decodeBody decodes the body into v with codec, with options if the codec
+// supports them.
+func decodeBody(codec Codec, r io.Reader, v any, options DecodeOptions) error {
+	if decoder, ok := codec.(OptionsDecoder); ok && options != (DecodeOptions{}) {
+		return decoder.DecodeWithOptions(r, v, options)
+	}
+
+	return codec.Decode(r, v)
+}
+
+// checkTrailingData returns an error if r has anything but whitespace left.
+func checkTrailingData(r io.Reader) error {
+	buffer := make([]byte, 512)
+	for {
+		n, err := r.Read(buffer)
+		for _, b := range buffer[:n] {
+			switch b {
+			case ' ', '\t', '\r', '\n':
+			default:
+				return errors.New("unexpected data after the body")
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package flex
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type sizedBody struct {
+	Name string `json:"name"`
+}
+
+// chunkedReader hides the length of its content, so the request is sent
+// without a Content-Length.
+type chunkedReader struct {
+	io.Reader
+}
+
+func TestMaxBodySize(t *testing.T) {
+	s := Default()
+	s.POST("/json", func(i *BasicInjector) Result {
+		return i.WrapOk(i.RequestBody())
+	}, sizedBody{}).WithBodyOptions(BodyOptions{MaxSize: 64})
+
+	s.POST("/bytes", func(i *BasicInjector) Result {
+		return i.WrapOk(len(i.RequestBody().([]byte)))
+	}, []byte{}).WithBodyOptions(BodyOptions{MaxSize: 64})
+
+	large := `{"name":"` + strings.Repeat("a", 128) + `"}`
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool
+		status  int
+	}{
+		{"json within limit", "/json", `{"name":"a"}`, false, http.StatusOK},
+		{"json with content length", "/json", large, false, http.StatusRequestEntityTooLarge},
+		{"chunked json", "/json", large, true, http.StatusRequestEntityTooLarge},
+		{"chunked json within limit", "/json", `{"name":"a"}`, true, http.StatusOK},
+		{"bytes with content length", "/bytes", large, false, http.StatusRequestEntityTooLarge},
+		{"chunked bytes", "/bytes", large, true, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(test.body)
+			if test.chunked {
+				body = chunkedReader{body}
+			}
+
+			r := httptest.NewRequest(http.MethodPost, test.path, body)
+			r.Header.Set("Content-Type", MediaTypeJSON)
+			if test.chunked {
+				r.ContentLength = -1
+			}
+
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, r)
+			if w.Code != test.status {
+				t.Fatalf("expected status %d, got %d: %s", test.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,465 @@
+package flex
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+	"io"
+	"mime"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	MediaTypeJSON        = "application/json"
+	MediaTypeXML         = "application/xml"
+	MediaTypeYAML        = "application/yaml"
+	MediaTypeMessagePack = "application/msgpack"
+	MediaTypeCBOR        = "application/cbor"
+	MediaTypeForm        = "application/x-www-form-urlencoded"
+	// MediaTypeMultipartForm bodies are decoded by RequestBody but have no
+	// codec, as they need the boundary of the Content-Type.
+	MediaTypeMultipartForm = "multipart/form-data"
+)
+
+// Codec encodes and decodes bodies of one media type. Codecs are registered on
+// the server by RegisterCodec and selected by the Content-Type of requests and
+// the Accept header for responses. Only the json and form codecs are
+// registered by default, see XMLCodec and the other constructors.
+type Codec interface {
+	MediaType() string
+	Marshal(v any) ([]byte, error)
+	Decode(r io.Reader, v any) error
+}
+
+type jsonCodec struct {
+	jsonHandler JsonHandler
+}
+
+func (jsonCodec) MediaType() string {
+	return MediaTypeJSON
+}
+
+func (c jsonCodec) Marshal(v any) ([]byte, error) {
+	return c.jsonHandler.Marshal(v)
+}
+
+func (c jsonCodec) Decode(r io.Reader, v any) error {
+	return c.jsonHandler.NewDecoder(r).Decode(v)
+}
+
+func (c jsonCodec) DecodeWithOptions(r io.Reader, v any, options DecodeOptions) error {
+	decoder := c.jsonHandler.NewDecoder(r)
+	if options.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+
+	if options.UseNumber {
+		decoder.UseNumber()
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	if options.DisallowTrailingData {
+		return checkTrailingData(io.MultiReader(decoder.Buffered(), r))
+	}
+
+	return nil
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) MediaType() string {
+	return MediaTypeXML
+}
+
+// Marshal marshals maps and slices, which encoding/xml does not support, as
+// a response element with an element per key or item.
+func (xmlCodec) Marshal(v any) ([]byte, error) {
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return xml.Marshal(xmlElement{v})
+	default:
+		return xml.Marshal(v)
+	}
+}
+
+func (xmlCodec) Decode(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+type xmlElement struct {
+	value any
+}
+
+func (x xmlElement) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if start.Name.Local == "xmlElement" {
+		start.Name.Local = "response"
+	}
+
+	v := reflect.ValueOf(x.value)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+
+		for _, key := range keys {
+			if err := e.EncodeElement(xmlElement{v.MapIndex(key).Interface()}, xml.StartElement{Name: xml.Name{Local: key.String()}}); err != nil {
+				return err
+			}
+		}
+
+		return e.EncodeToken(start.End())
+	case v.Kind() == reflect.Array || v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+
+		for index := 0; index < v.Len(); index++ {
+			if err := e.EncodeElement(xmlElement{v.Index(index).Interface()}, xml.StartElement{Name: xml.Name{Local: "item"}}); err != nil {
+				return err
+			}
+		}
+
+		return e.EncodeToken(start.End())
+	default:
+		return e.EncodeElement(v.Interface(), start)
+	}
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) MediaType() string {
+	return MediaTypeYAML
+}
+
+func (yamlCodec) Marshal(v any) ([]byte, error) {
+	return yaml.Marshal(v)
+}
+
+func (yamlCodec) Decode(r io.Reader, v any) error {
+	return yaml.NewDecoder(r).Decode(v)
+}
+
+type messagePackCodec struct{}
+
+func (messagePackCodec) MediaType() string {
+	return MediaTypeMessagePack
+}
+
+func (messagePackCodec) Marshal(v any) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (messagePackCodec) Decode(r io.Reader, v any) error {
+	decoder := msgpack.NewDecoder(r)
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(v)
+}
+
+type cborCodec struct{}
+
+func (cborCodec) MediaType() string {
+	return MediaTypeCBOR
+}
+
+func (cborCodec) Marshal(v any) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (cborCodec) Decode(r io.Reader, v any) error {
+	return cbor.NewDecoder(r).Decode(v)
+}
+
+type formCodec struct{}
+
+func (formCodec) MediaType() string {
+	return MediaTypeForm
+}
+
+func (formCodec) Marshal(v any) ([]byte, error) {
+	values, err := encodeForm(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(values.Encode()), nil
+}
+
+func (formCodec) Decode(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	return decodeForm(values, v)
+}
+
+// codecAliases maps common alternative media types to the registered ones.
+var codecAliases = map[string]string{
+	"text/json":               MediaTypeJSON,
+	"text/xml":                MediaTypeXML,
+	"text/yaml":               MediaTypeYAML,
+	"application/x-yaml":      MediaTypeYAML,
+	"application/x-msgpack":   MediaTypeMessagePack,
+	"application/vnd.msgpack": MediaTypeMessagePack,
+}
+
+// codecRegistry holds the codecs of a server in order of preference, which
+// breaks the ties of the Accept header, so json is used when any media type
+// is accepted.
+type codecRegistry struct {
+	codecs []Codec
+	*sync.RWMutex
+}
+
+func newCodecRegistry(jsonHandler JsonHandler) *codecRegistry {
+	return &codecRegistry{
+		codecs:  []Codec{jsonCodec{jsonHandler}, formCodec{}},
+		RWMutex: &sync.RWMutex{},
+	}
+}
+
+func (r *codecRegistry) clone() *codecRegistry {
+	r.RLock()
+	defer r.RUnlock()
+
+	return &codecRegistry{codecs: slices.Clone(r.codecs), RWMutex: &sync.RWMutex{}}
+}
+
+// register replaces the codec of the same media type or appends codec.
+func (r *codecRegistry) register(codec Codec) {
+	r.Lock()
+	defer r.Unlock()
+
+	mediaType := strings.ToLower(codec.MediaType())
+	for index, c := range r.codecs {
+		if strings.ToLower(c.MediaType()) == mediaType {
+			r.codecs[index] = codec
+			return
+		}
+	}
+
+	r.codecs = append(r.codecs, codec)
+}
+
+func (r *codecRegistry) remove(mediaType string) {
+	r.Lock()
+	defer r.Unlock()
+
+	mediaType = strings.ToLower(mediaType)
+	r.codecs = slices.DeleteFunc(r.codecs, func(c Codec) bool {
+		return strings.ToLower(c.MediaType()) == mediaType
+	})
+}
+
+func (r *codecRegistry) all() []Codec {
+	r.RLock()
+	defer r.RUnlock()
+
+	return slices.Clone(r.codecs)
+}
+
+// lookup returns the codec of mediaType, also matching aliases and structured
+// syntax suffixes, e.g. application/problem+json is handled by the json codec.
+func (r *codecRegistry) lookup(mediaType string) Codec {
+	return r.find(mediaType, true)
+}
+
+func (r *codecRegistry) find(mediaType string, matchSuffix bool) Codec {
+	r.RLock()
+	defer r.RUnlock()
+
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	candidates := []string{mediaType}
+	if alias, exist := codecAliases[mediaType]; exist {
+		candidates = append(candidates, alias)
+	}
+
+	if index := strings.LastIndexByte(mediaType, '+'); matchSuffix && index != -1 {
+		candidates = append(candidates, "application/"+mediaType[index+1:])
+	}
+
+	for _, candidate := range candidates {
+		for _, c := range r.codecs {
+			if strings.ToLower(c.MediaType()) == candidate {
+				return c
+			}
+		}
+	}
+
+	return nil
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiatedCodec is a codec acceptable by the client and the media type to
+// respond with.
+type negotiatedCodec struct {
+	codec       Codec
+	contentType string
+}
+
+// negotiate returns the codecs acceptable by the Accept header in order of
+// preference, by quality and then by order of registration, so json is kept
+// for anything accepted as much. The quality of a codec is the one of the most
+// specific range matching it. A missing Accept header accepts anything.
+// Structured syntax suffixes are not matched, as e.g. application/xhtml+xml
+// accepted by browsers is not a plain xml body.
+func (r *codecRegistry) negotiate(accept string) []negotiatedCodec {
+	codecs := r.all()
+	if strings.TrimSpace(accept) == "" {
+		negotiated := make([]negotiatedCodec, len(codecs))
+		for index, c := range codecs {
+			negotiated[index] = negotiatedCodec{c, c.MediaType()}
+		}
+
+		return negotiated
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, exist := params["q"]; exist {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		ranges = append(ranges, mediaRange{mediaType, q})
+	}
+
+	type candidate struct {
+		negotiatedCodec
+		q float64
+	}
+
+	var candidates []candidate
+	for _, c := range codecs {
+		mediaType := strings.ToLower(c.MediaType())
+		q, specificity := 0.0, 0
+		for _, mr := range ranges {
+			matched := 0
+			switch {
+			case mr.mediaType == "*/*":
+				matched = 1
+			case strings.HasSuffix(mr.mediaType, "/*"):
+				if strings.HasPrefix(mediaType, strings.TrimSuffix(mr.mediaType, "*")) {
+					matched = 2
+				}
+			case mr.mediaType == mediaType || codecAliases[mr.mediaType] == mediaType:
+				matched = 3
+			}
+
+			if matched > specificity {
+				q, specificity = mr.q, matched
+			}
+		}
+
+		if q > 0 {
+			candidates = append(candidates, candidate{negotiatedCodec{c, c.MediaType()}, q})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	negotiated := make([]negotiatedCodec, len(candidates))
+	for index, c := range candidates {
+		negotiated[index] = c.negotiatedCodec
+	}
+
+	return negotiated
+}
+
+// XMLCodec returns the application/xml codec, which is not registered by
+// default, e.g. s.RegisterCodec(XMLCodec()). Maps and slices are marshalled
+// in a <response> root element.
+func XMLCodec() Codec {
+	return xmlCodec{}
+}
+
+// YAMLCodec returns the application/yaml codec, which is not registered by
+// default.
+func YAMLCodec() Codec {
+	return yamlCodec{}
+}
+
+// MessagePackCodec returns the application/msgpack codec, which is not
+// registered by default.
+func MessagePackCodec() Codec {
+	return messagePackCodec{}
+}
+
+// CBORCodec returns the application/cbor codec, which is not registered by
+// default.
+func CBORCodec() Codec {
+	return cborCodec{}
+}
+
+// RegisterCodec registers codec for its media type, replacing the codec
+// registered for it before. It applies to the server and the groups created
+// after the call.
+func (s *Server[I]) RegisterCodec(codec Codec) *Server[I] {
+	if codec == nil {
+		panic("codec can not be nil")
+	}
+
+	s.codecs.register(codec)
+	return s
+}
+
+// RemoveCodec stops the server from accepting and producing mediaType.
+func (s *Server[I]) RemoveCodec(mediaType string) *Server[I] {
+	s.codecs.remove(mediaType)
+	return s
+}
+
+// Codecs returns the registered codecs in order of preference.
+func (s *Server[_]) Codecs() []Codec {
+	return s.codecs.all()
+}
@@ -0,0 +1,137 @@
+package flex
+
+import (
+	. "github.com/amirdlt/flex/util"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StrongETag returns a strong entity tag of the representation data.
+func StrongETag(data []byte) string {
+	return `"` + Sha256(string(data))[:32] + `"`
+}
+
+// WeakETag returns a weak entity tag of the representation data.
+func WeakETag(data []byte) string {
+	return "W/" + StrongETag(data)
+}
+
+// ETagOf returns a weak entity tag of the value v, e.g. a document loaded from
+// the database, which is cheaper than marshalling it to compare versions.
+func ETagOf(v any) string {
+	return `W/"` + Sha256OfHash(v)[:32] + `"`
+}
+
+// Preconditions evaluates the conditional headers of the request against the
+// current etag and last modification time of the resource, in the order of
+// RFC 9110. It returns 304, 412 or 0 if the request should be processed.
+// Empty etag and zero lastModified mean they are unknown.
+func Preconditions(i Injector, etag string, lastModified time.Time) int {
+	method := i.Method()
+	safe := method == http.MethodGet || method == http.MethodHead
+	lastModified = lastModified.Truncate(time.Second)
+
+	if ifMatch := i.GetRequestHeader("If-Match"); ifMatch != "" {
+		if !matchETag(ifMatch, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := parseHTTPTime(i.GetRequestHeader("If-Unmodified-Since")); ok && !lastModified.IsZero() {
+		if lastModified.After(since) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if ifNoneMatch := i.GetRequestHeader("If-None-Match"); ifNoneMatch != "" {
+		if matchETag(ifNoneMatch, etag, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := parseHTTPTime(i.GetRequestHeader("If-Modified-Since")); ok && safe && !lastModified.IsZero() {
+		if !lastModified.After(since) {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
+
+// matchETag reports whether etag is in the list of a conditional header, weak
+// comparison ignores the W/ prefix. * matches any existing representation.
+func matchETag(list, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		return strings.TrimSpace(list) == "*"
+	}
+
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseHTTPTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	t, err := http.ParseTime(value)
+	return t, err == nil
+}
+
+func (s *BasicInjector) SetETag(etag string) {
+	s.SetResponseHeader("ETag", etag)
+}
+
+func (s *BasicInjector) SetLastModified(lastModified time.Time) {
+	s.SetResponseHeader("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+}
+
+// CheckPreconditions evaluates the conditional headers against the current
+// version of the resource and panics with 304 or 412 if the request should not
+// be processed, e.g. to reject a PUT with a stale If-Match. The etag and last
+// modification time are set on the response, except on writes which go on,
+// as they change the version.
+func (s *BasicInjector) CheckPreconditions(etag string, lastModified time.Time) {
+	status := Preconditions(s, etag, lastModified)
+	if method := s.Method(); status != 0 || method == http.MethodGet || method == http.MethodHead {
+		if etag != "" {
+			s.SetETag(etag)
+		}
+
+		if !lastModified.IsZero() {
+			s.SetLastModified(lastModified)
+		}
+	}
+
+	switch status {
+	case http.StatusNotModified:
+		panic(s.Wrap(nil, http.StatusNotModified))
+	case http.StatusPreconditionFailed:
+		panic(s.WrapPreconditionFailedErr("precondition failed, the resource has been modified"))
+	}
+}
+
+func (s *BasicInjector) WrapPreconditionFailedErr(err any) Result {
+	return s.WrapJsonErr(err, s.defaultErrorCodes[http.StatusPreconditionFailed], http.StatusPreconditionFailed)
+}
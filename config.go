@@ -0,0 +1,132 @@
+package flex
+
+import (
+	"crypto/tls"
+	"fmt"
+	. "github.com/amirdlt/flex/util"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Keys of the config given to New which are understood by flex itself.
+const (
+	// ConfigLoggerOut is the path of a file the logs are appended to instead of stderr.
+	ConfigLoggerOut = "logger_out"
+	// ConfigServer is an *http.Server with a nil Handler, to tune timeouts, limits, etc.
+	ConfigServer = "server"
+	// ConfigShutdownTimeout bounds draining of in-flight requests on shutdown, 30s by default.
+	ConfigShutdownTimeout = "shutdown_timeout"
+	// ConfigShutdownDelay is waited on shutdown after reporting not ready, before draining, 0 by default.
+	ConfigShutdownDelay = "shutdown_delay"
+	// ConfigTLS is the *tls.Config used by RunTLS and ServeTLS.
+	ConfigTLS = "tls"
+	// ConfigProduction hides the messages of internal errors from the responses, see SetProduction.
+	ConfigProduction = "production"
+)
+
+type ConfigKey struct {
+	Name        string
+	Type        reflect.Type
+	Description string
+}
+
+// ConfigSchema documents the known config keys. Values of these keys are checked
+// by New, while the other keys are left for the application.
+var ConfigSchema = []ConfigKey{
+	{ConfigLoggerOut, reflect.TypeOf(""), "path of a file the logs are appended to instead of stderr"},
+	{ConfigServer, reflect.TypeOf((*http.Server)(nil)), "*http.Server with a nil Handler"},
+	{ConfigShutdownTimeout, reflect.TypeOf(time.Duration(0)), "max duration of draining in-flight requests on shutdown"},
+	{ConfigShutdownDelay, reflect.TypeOf(time.Duration(0)), "delay between reporting not ready and draining on shutdown"},
+	{ConfigTLS, reflect.TypeOf((*tls.Config)(nil)), "*tls.Config used by RunTLS and ServeTLS"},
+	{ConfigProduction, reflect.TypeOf(false), "hides the messages of internal errors from the responses"},
+}
+
+func validateConfig(config M) {
+	for _, key := range ConfigSchema {
+		value, exist := config[key.Name]
+		if !exist {
+			continue
+		}
+
+		if err := SetFromAny(reflect.New(key.Type).Elem(), value); err != nil {
+			panic(fmt.Sprintf("invalid config %s (%s), expected %s: %s", key.Name, key.Description, key.Type, err))
+		}
+	}
+}
+
+func lookupConfig(config M, key string) (any, bool) {
+	if v, exist := config[key]; exist {
+		return v, true
+	}
+
+	// dot separated keys of nested maps, e.g. db.url
+	path := strings.Split(key, ".")
+	for index, k := range path {
+		v, exist := config[k]
+		if !exist {
+			return nil, false
+		}
+
+		if index == len(path)-1 {
+			return v, true
+		}
+
+		if config, exist = v.(M); !exist {
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+func configValue[T any](s interface{ LookupConfig(string) (any, bool) }, key string, defaultValue []T) T {
+	var value T
+	raw, exist := s.LookupConfig(key)
+	if !exist {
+		if len(defaultValue) != 0 {
+			value = defaultValue[0]
+		}
+
+		return value
+	}
+
+	if err := SetFromAny(reflect.ValueOf(&value).Elem(), raw); err != nil {
+		panic(fmt.Sprintf("invalid config %s, expected %T: %s", key, value, err))
+	}
+
+	return value
+}
+
+func (s *Server[_]) ConfigString(key string, defaultValue ...string) string {
+	return configValue(s, key, defaultValue)
+}
+
+func (s *Server[_]) ConfigInt(key string, defaultValue ...int) int {
+	return configValue(s, key, defaultValue)
+}
+
+func (s *Server[_]) ConfigInt64(key string, defaultValue ...int64) int64 {
+	return configValue(s, key, defaultValue)
+}
+
+func (s *Server[_]) ConfigFloat64(key string, defaultValue ...float64) float64 {
+	return configValue(s, key, defaultValue)
+}
+
+func (s *Server[_]) ConfigBool(key string, defaultValue ...bool) bool {
+	return configValue(s, key, defaultValue)
+}
+
+func (s *Server[_]) ConfigDuration(key string, defaultValue ...time.Duration) time.Duration {
+	return configValue(s, key, defaultValue)
+}
+
+func (s *Server[_]) ConfigTime(key string, defaultValue ...time.Time) time.Time {
+	return configValue(s, key, defaultValue)
+}
+
+func (s *Server[_]) ConfigStrings(key string, defaultValue ...[]string) []string {
+	return configValue(s, key, defaultValue)
+}
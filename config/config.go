@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"github.com/amirdlt/ffvm"
+	. "github.com/amirdlt/flex/util"
+	"strings"
+)
+
+// Source provides a layer of configuration as a (possibly nested) map.
+type Source interface {
+	Load() (M, error)
+}
+
+type SourceFunc func() (M, error)
+
+func (f SourceFunc) Load() (M, error) {
+	return f()
+}
+
+type ValidationError struct {
+	Issues []ffvm.ValidatorIssue
+}
+
+func (e ValidationError) Error() string {
+	issues := make([]string, len(e.Issues))
+	for index, issue := range e.Issues {
+		issues[index] = fmt.Sprintf("%+v", issue)
+	}
+
+	return "invalid configuration: " + strings.Join(issues, ", ")
+}
+
+// Load loads all the sources and merges them in order, so later sources override
+// the earlier ones, e.g. Load(File("config.yaml"), Env("APP_"), Flags(flag.CommandLine)).
+func Load(sources ...Source) (M, error) {
+	config := M{}
+	for _, source := range sources {
+		layer, err := source.Load()
+		if err != nil {
+			return nil, err
+		}
+
+		merge(config, layer)
+	}
+
+	return config, nil
+}
+
+// LoadInto loads the sources like Load and decodes the result into dst, see Decode.
+// The merged map is returned as well, so it can be passed to flex.New.
+func LoadInto(dst any, sources ...Source) (M, error) {
+	config, err := Load(sources...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Decode(config, dst); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func merge(dst, src M) {
+	for k, v := range src {
+		if srcM, ok := v.(M); ok {
+			if dstM, ok := dst[k].(M); ok {
+				merge(dstM, srcM)
+				continue
+			}
+
+			copied := M{}
+			merge(copied, srcM)
+			v = copied
+		}
+
+		dst[k] = v
+	}
+}
+
+// set puts value in config by a dot separated path, creating the nested maps.
+func set(config M, path string, value any) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		inner, ok := config[key].(M)
+		if !ok {
+			inner = M{}
+			config[key] = inner
+		}
+
+		config = inner
+	}
+
+	config[keys[len(keys)-1]] = value
+}
+
+// normalize converts the nested maps decoded by yaml or json into M.
+func normalize(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for k, inner := range value {
+			value[k] = normalize(inner)
+		}
+
+		return value
+	case map[any]any:
+		m := M{}
+		for k, inner := range value {
+			m[fmt.Sprint(k)] = normalize(inner)
+		}
+
+		return m
+	case []any:
+		for index, inner := range value {
+			value[index] = normalize(inner)
+		}
+
+		return value
+	default:
+		return v
+	}
+}
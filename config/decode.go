@@ -0,0 +1,125 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"github.com/amirdlt/ffvm"
+	. "github.com/amirdlt/flex/util"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Decode fills the struct pointed by dst from config. The key of a field is taken
+// from its config tag, then its json tag and finally its snake-cased name. Nested
+// structs are decoded from nested maps, missing values are set from the default
+// tag, e.g. `config:"shutdown_timeout" default:"30s"`, and finally the ffvm tags of
+// dst are validated.
+func Decode(config M, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("config can only be decoded into a non-nil pointer to a struct")
+	}
+
+	var errs []error
+	decodeStruct(config, v.Elem(), "", &errs)
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+
+	if issues := ffvm.Validate(dst); len(issues) != 0 {
+		return ValidationError{Issues: issues}
+	}
+
+	return nil
+}
+
+func decodeStruct(config M, v reflect.Value, path string, errs *[]error) {
+	t := v.Type()
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, tagged := fieldKey(field)
+		if name == "-" {
+			continue
+		}
+
+		fieldValue := v.Field(index)
+		if isNested(field.Type) {
+			if field.Anonymous && !tagged {
+				decodeStruct(config, fieldValue, path, errs)
+				continue
+			}
+
+			inner, ok := config[name].(M)
+			if !ok {
+				if _, exist := config[name]; exist {
+					*errs = append(*errs, fmt.Errorf("%s%s: expected an object, got %T", path, name, config[name]))
+					continue
+				}
+
+				inner = M{}
+			}
+
+			decodeStruct(inner, fieldValue, path+name+".", errs)
+			continue
+		}
+
+		value, exist := config[name]
+		if !exist {
+			if defaultValue, ok := field.Tag.Lookup("default"); ok {
+				if err := SetFromString(fieldValue, defaultValue); err != nil {
+					*errs = append(*errs, fmt.Errorf("%s%s: invalid default: %w", path, name, err))
+				}
+			}
+
+			continue
+		}
+
+		if err := SetFromAny(fieldValue, value); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s%s: %w", path, name, err))
+		}
+	}
+}
+
+func fieldKey(field reflect.StructField) (string, bool) {
+	for _, tag := range []string{"config", "json"} {
+		if value, ok := field.Tag.Lookup(tag); ok {
+			if name, _, _ := strings.Cut(value, ","); name != "" {
+				return name, true
+			}
+		}
+	}
+
+	return snakeCase(field.Name), false
+}
+
+func isNested(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct &&
+		t != reflect.TypeOf(time.Time{}) &&
+		!reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem())
+}
+
+func snakeCase(name string) string {
+	var builder strings.Builder
+	runes := []rune(name)
+	for index, r := range runes {
+		if unicode.IsUpper(r) {
+			if index > 0 && (unicode.IsLower(runes[index-1]) ||
+				index+1 < len(runes) && unicode.IsLower(runes[index+1])) {
+				builder.WriteByte('_')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		builder.WriteRune(r)
+	}
+
+	return builder.String()
+}
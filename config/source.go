@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	. "github.com/amirdlt/flex/util"
+	"github.com/goccy/go-json"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// File loads a json, yaml or dotenv file, chosen by the extension of path.
+// Keys of dotenv files are mapped like the ones of Env with no prefix.
+func File(path string) Source {
+	return SourceFunc(func() (M, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		config, err := parse(filepath.Ext(path), data)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse config file %s: %w", path, err)
+		}
+
+		return config, nil
+	})
+}
+
+// OptionalFile is like File but loads nothing if the file does not exist.
+func OptionalFile(path string) Source {
+	return SourceFunc(func() (M, error) {
+		config, err := File(path).Load()
+		if errors.Is(err, fs.ErrNotExist) {
+			return M{}, nil
+		}
+
+		return config, err
+	})
+}
+
+func parse(ext string, data []byte) (M, error) {
+	config := M{}
+	switch strings.ToLower(ext) {
+	case ".json":
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+	case ".env":
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+
+			key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected KEY=VALUE", line)
+			}
+
+			value = strings.TrimSpace(value)
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			} else if len(value) > 1 && value[0] == '\'' && value[len(value)-1] == '\'' {
+				value = value[1 : len(value)-1]
+			}
+
+			set(config, envKey(strings.TrimSpace(key)), value)
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unsupported config file extension: " + ext)
+	}
+
+	return normalize(config).(M), nil
+}
+
+// Env loads the environment variables starting with prefix. The prefix is
+// dropped and the rest is lower-cased, with "__" separating nested keys, so
+// APP_LOGGER_OUT becomes logger_out and APP_DB__URL becomes db.url.
+func Env(prefix string) Source {
+	return SourceFunc(func() (M, error) {
+		config := M{}
+		for _, env := range os.Environ() {
+			key, value, _ := strings.Cut(env, "=")
+			if !strings.HasPrefix(key, prefix) || key == prefix {
+				continue
+			}
+
+			set(config, envKey(strings.TrimPrefix(key, prefix)), value)
+		}
+
+		return config, nil
+	})
+}
+
+func envKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "__", ".")
+}
+
+// Flags loads the flags which are set explicitly on the command line, so the
+// flag defaults do not override the earlier sources. Dashes in flag names are
+// mapped to underscores and dots separate nested keys, e.g. -db.url.
+func Flags(flagSet *flag.FlagSet) Source {
+	return SourceFunc(func() (M, error) {
+		if !flagSet.Parsed() {
+			return nil, errors.New("flags must be parsed before loading the config")
+		}
+
+		config := M{}
+		flagSet.Visit(func(f *flag.Flag) {
+			var value any = f.Value.String()
+			if getter, ok := f.Value.(flag.Getter); ok {
+				value = getter.Get()
+			}
+
+			set(config, strings.ReplaceAll(f.Name, "-", "_"), value)
+		})
+
+		return config, nil
+	})
+}
+
+// Static is a source of fixed values, useful for defaults or tests.
+func Static(config M) Source {
+	return SourceFunc(func() (M, error) {
+		return config, nil
+	})
+}
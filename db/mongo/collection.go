@@ -12,6 +12,10 @@ import (
 	"time"
 )
 
+// ErrNoDocuments is returned by the queries of a single document which match
+// none, flex responds to it with 404.
+var ErrNoDocuments = mongo.ErrNoDocuments
+
 type Collection struct {
 	*mongo.Collection
 }
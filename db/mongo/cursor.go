@@ -0,0 +1,47 @@
+package mongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"iter"
+)
+
+// Seq iterates the cursor, decoding each document into a T. The cursor is
+// closed when the iteration ends, e.g. to stream a Find result:
+// flex.StreamSeq2(mongo.Seq[User](ctx, cursor), flex.StreamNDJSON).
+func Seq[T any](ctx context.Context, cursor *mongo.Cursor) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var item T
+			if err := cursor.Decode(&item); err != nil {
+				yield(item, err)
+				return
+			}
+
+			if !yield(item, nil) {
+				return
+			}
+		}
+
+		if err := cursor.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// FindSeq runs Find on the collection and iterates the result, see Seq.
+func FindSeq[T any](ctx context.Context, c *Collection, filter any, opts ...*options.FindOptions) iter.Seq2[T, error] {
+	cursor, err := c.Find(ctx, filter, opts...)
+	if err != nil {
+		return func(yield func(T, error) bool) {
+			var zero T
+			yield(zero, err)
+		}
+	}
+
+	return Seq[T](ctx, cursor)
+}
@@ -0,0 +1,261 @@
+package flex
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	. "github.com/amirdlt/flex/util"
+	"github.com/goccy/go-json"
+	"gopkg.in/yaml.v3"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed assets/docs
+var embeddedAssets embed.FS
+
+// DocsLayout arranges the embedded UI, which is built in and needs no
+// external resources, it does not load a third party renderer.
+type DocsLayout string
+
+const (
+	// DocsLayoutSidebar shows a navigation bar and the operations expanded,
+	// like RapiDoc.
+	DocsLayoutSidebar DocsLayout = "sidebar"
+	// DocsLayoutCollapsible shows a single column of collapsible operations
+	// grouped by tag, like Swagger UI.
+	DocsLayoutCollapsible DocsLayout = "collapsible"
+	// DocsLayoutThreeColumn shows a navigation bar, the documentation and a
+	// column of samples, like Redoc. It has no try it panel.
+	DocsLayoutThreeColumn DocsLayout = "three-column"
+)
+
+const (
+	DocsThemeLight = "light"
+	DocsThemeDark  = "dark"
+)
+
+// DocsOptions configures the documentation UI served by ServeDocs. The
+// document is taken from Spec, SpecFile or Generate, in this order. The
+// layout is DocsLayoutSidebar and the theme DocsThemeDark by default.
+type DocsOptions struct {
+	Title        string
+	Layout       DocsLayout
+	Theme        string
+	PrimaryColor string
+	TryIt        bool
+
+	// Spec is an in-memory json or yaml document.
+	Spec []byte
+	// SpecFile is read on each request, so it can change without a restart.
+	SpecFile string
+	// SpecContentType is detected from Spec or SpecFile if empty.
+	SpecContentType string
+	// Generate generates the document from the registered routes on the
+	// first request.
+	Generate *OpenAPIInfo
+
+	// Assets replaces the embedded UI. Its index.html is executed as an
+	// html/template with the same data as the embedded one, and the other
+	// files are served under path/assets.
+	Assets fs.FS
+}
+
+type docsPage struct {
+	Title        string
+	Layout       DocsLayout
+	Theme        string
+	PrimaryColor string
+	TryIt        bool
+	AssetsPath   string
+	SpecURL      string
+}
+
+// ServeDocs serves the documentation UI at path, its assets at path/assets and
+// the document at path/raw, all from the server itself with no external
+// resources. path/raw?format=json converts a yaml document to json.
+func (s *Server[I]) ServeDocs(path string, options DocsOptions) {
+	path = strings.TrimSuffix(path, "/")
+	if options.Spec == nil && options.SpecFile == "" && options.Generate == nil {
+		panic("one of Spec, SpecFile or Generate must be set to serve docs")
+	}
+
+	if options.Layout == "" {
+		options.Layout = DocsLayoutSidebar
+	}
+
+	switch options.Layout {
+	case DocsLayoutSidebar, DocsLayoutCollapsible, DocsLayoutThreeColumn:
+	default:
+		panic("unknown docs layout: " + string(options.Layout))
+	}
+
+	// dark like the former RapiDoc UI of ServeDefaultOpenAPI
+	if options.Theme == "" {
+		options.Theme = DocsThemeDark
+	}
+
+	if options.PrimaryColor == "" {
+		options.PrimaryColor = "#2f81f7"
+	}
+
+	if options.Title == "" {
+		options.Title = "API Documentation"
+		if options.Generate != nil && options.Generate.Title != "" {
+			options.Title = options.Generate.Title
+		}
+	}
+
+	assets := options.Assets
+	if assets == nil {
+		var err error
+		if assets, err = fs.Sub(embeddedAssets, "assets/docs"); err != nil {
+			panic(err)
+		}
+	}
+
+	index, err := template.ParseFS(assets, "index.html")
+	if err != nil {
+		panic("could not parse docs index.html, err=" + err.Error())
+	}
+
+	fullPath := s.rootPath + path
+	page := docsPage{
+		Title:        options.Title,
+		Layout:       options.Layout,
+		Theme:        options.Theme,
+		PrimaryColor: options.PrimaryColor,
+		TryIt:        options.TryIt,
+		AssetsPath:   fullPath + "/assets",
+		SpecURL:      fullPath + "/raw?format=json",
+	}
+
+	s.GET(path, func(i I) Result {
+		var buffer bytes.Buffer
+		if err := index.Execute(&buffer, page); err != nil {
+			return Result{responseBody: M{"error": "could not render docs, err=" + err.Error()}, statusCode: http.StatusInternalServerError}
+		}
+
+		i.SetContentType("text/html; charset=utf-8")
+		return i.WrapOk(buffer.Bytes())
+	}, noBody).HideFromDocs()
+
+	fileServer := http.StripPrefix(page.AssetsPath, http.FileServer(http.FS(assets)))
+	s.GET(path+"/assets/*file", func(i I) Result {
+		fileServer.ServeHTTP(i.response(), i.request())
+		return Result{terminate: true}
+	}, noBody).HideFromDocs()
+
+	spec := s.docsSpec(options)
+	s.GET(path+"/raw", func(i I) Result {
+		doc, contentType, err := spec()
+		if err != nil {
+			return Result{responseBody: M{"error": err.Error()}, statusCode: http.StatusInternalServerError}
+		}
+
+		if i.URL().Query().Get("format") == "json" && !strings.Contains(contentType, "json") {
+			if doc, err = yamlToJson(doc); err != nil {
+				return Result{responseBody: M{"error": "could not convert document to json, err=" + err.Error()}, statusCode: http.StatusInternalServerError}
+			}
+
+			contentType = "application/json"
+		}
+
+		i.SetContentType(contentType)
+		return i.WrapOk(doc)
+	}, noBody).HideFromDocs()
+}
+
+// docsSpec returns a function providing the document and its content type.
+func (s *Server[I]) docsSpec(options DocsOptions) func() ([]byte, string, error) {
+	switch {
+	case options.Spec != nil:
+		contentType := options.SpecContentType
+		if contentType == "" {
+			contentType = "text/yaml"
+			if json.Valid(options.Spec) {
+				contentType = "application/json"
+			}
+		}
+
+		return func() ([]byte, string, error) {
+			return options.Spec, contentType, nil
+		}
+	case options.SpecFile != "":
+		contentType := options.SpecContentType
+		if contentType == "" {
+			contentType = "text/yaml"
+			if strings.HasSuffix(options.SpecFile, ".json") {
+				contentType = "application/json"
+			}
+		}
+
+		return func() ([]byte, string, error) {
+			doc, err := os.ReadFile(options.SpecFile)
+			if err != nil {
+				return nil, "", fmt.Errorf("could not read openapi document, err=%w", err)
+			}
+
+			return doc, contentType, nil
+		}
+	default:
+		var doc []byte
+		var mutex sync.Mutex
+		return func() ([]byte, string, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if doc == nil {
+				generated, err := s.jsonHandler.Marshal(s.OpenAPIDocument(*options.Generate))
+				if err != nil {
+					return nil, "", fmt.Errorf("could not generate openapi document, err=%w", err)
+				}
+
+				doc = generated
+			}
+
+			return doc, "application/json", nil
+		}
+	}
+}
+
+func yamlToJson(doc []byte) ([]byte, error) {
+	var value any
+	if err := yaml.Unmarshal(doc, &value); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonCompatible(value))
+}
+
+// jsonCompatible converts the map[any]any values yaml produces for non-string
+// keys, e.g. response status codes, to string keyed maps.
+func jsonCompatible(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for k, inner := range value {
+			value[k] = jsonCompatible(inner)
+		}
+
+		return value
+	case map[any]any:
+		m := M{}
+		for k, inner := range value {
+			m[fmt.Sprint(k)] = jsonCompatible(inner)
+		}
+
+		return m
+	case []any:
+		for index, inner := range value {
+			value[index] = jsonCompatible(inner)
+		}
+
+		return value
+	default:
+		return v
+	}
+}
@@ -0,0 +1,140 @@
+package flex
+
+import (
+	"errors"
+	"github.com/amirdlt/flex/db/mongo"
+	"net/http"
+	"reflect"
+)
+
+// errorMapping maps the errors which match it to an error response.
+type errorMapping struct {
+	match      func(err error) bool
+	statusCode int
+	code       string
+}
+
+// defaultErrorMappings are used after the mappings of the server and its
+// parents.
+var defaultErrorMappings = []errorMapping{
+	{func(err error) bool { return errors.Is(err, mongo.ErrNoDocuments) }, http.StatusNotFound, ""},
+}
+
+// MapError maps the errors returned by the handlers of s and its groups which
+// are target, as reported by errors.Is, to statusCode. The code defaults to
+// the default error code of statusCode.
+func (s *Server[I]) MapError(target error, statusCode int, code ...string) *Server[I] {
+	if target == nil {
+		panic("target error can not be nil")
+	}
+
+	return s.mapError(func(err error) bool { return errors.Is(err, target) }, statusCode, code)
+}
+
+// MapErrorType maps the errors returned by the handlers of s and its groups
+// which are of type E, as reported by errors.As, to statusCode.
+func MapErrorType[E error, I Injector](s *Server[I], statusCode int, code ...string) *Server[I] {
+	if reflect.TypeFor[E]().Kind() == reflect.Interface {
+		panic("error type must be a concrete type, got " + reflect.TypeFor[E]().String())
+	}
+
+	return s.mapError(func(err error) bool {
+		var target E
+		return errors.As(err, &target)
+	}, statusCode, code)
+}
+
+func (s *Server[I]) mapError(match func(err error) bool, statusCode int, code []string) *Server[I] {
+	if http.StatusText(statusCode) == "" {
+		panic("invalid status code for error mapping")
+	}
+
+	var c string
+	switch len(code) {
+	case 0:
+	case 1:
+		c = code[0]
+	default:
+		panic("code must be one arg at max")
+	}
+
+	s.errorMappings = append(s.errorMappings, errorMapping{match, statusCode, c})
+	return s
+}
+
+// lookupErrorMapping returns the first mapping of err, from the mappings of s
+// to the ones of the root and then the default ones.
+func (s *Server[I]) lookupErrorMapping(err error) (errorMapping, bool) {
+	for server := s; server != nil; server = server.parent {
+		for _, mapping := range server.errorMappings {
+			if mapping.match(err) {
+				return mapping, true
+			}
+		}
+	}
+
+	for _, mapping := range defaultErrorMappings {
+		if mapping.match(err) {
+			return mapping, true
+		}
+	}
+
+	return errorMapping{}, false
+}
+
+// SetProduction hides the messages of the unmapped errors returned by the
+// handlers behind a generic one, they are logged instead. It overrides the
+// production config.
+func (s *Server[I]) SetProduction(production bool) *Server[I] {
+	s.root().production = production
+	return s
+}
+
+func (s *Server[I]) IsProduction() bool {
+	return s.root().production
+}
+
+// ErrorResult converts err to an error response by the error mappings of s.
+// Unmapped errors are internal errors, whose messages are hidden in
+// production.
+func (s *Server[I]) ErrorResult(i I, err error) Result {
+	if mapping, ok := s.lookupErrorMapping(err); ok {
+		code := mapping.code
+		if code == "" {
+			code = s.defaultErrorCodes[mapping.statusCode]
+		}
+
+		return i.WrapJsonErr(err.Error(), code, mapping.statusCode)
+	}
+
+	if s.IsProduction() {
+		s.LogErrorf("%s %s: %v", i.Method(), i.URL().Path, err)
+		return i.WrapInternalErr("internal server error")
+	}
+
+	return i.WrapInternalErr(err.Error())
+}
+
+// errorHandler adapts a handler returning a response and an error. A nil
+// response, also a nil pointer, is sent as 204 and a Result as is.
+func (s *Server[I]) errorHandler(h func(I) (any, error)) Handler[I] {
+	return func(i I) Result {
+		response, err := h(i)
+		if err != nil {
+			return s.ErrorResult(i, err)
+		}
+
+		switch r := response.(type) {
+		case nil:
+			return i.WrapNoContent()
+		case Result:
+			return r
+		default:
+			if v := reflect.ValueOf(r); v.Kind() == reflect.Pointer && v.IsNil() {
+				return i.WrapNoContent()
+			}
+
+			return i.WrapOk(r)
+		}
+	}
+}
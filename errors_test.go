@@ -0,0 +1,30 @@
+package flex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNilPointerResponse(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	s := Default()
+	s.GET("/any", func(i *BasicInjector) (any, error) {
+		return (*user)(nil), nil
+	}, NoBody{})
+
+	GET(s, "/typed", func(i *BasicInjector, _ NoBody) (*user, error) {
+		return nil, nil
+	})
+
+	for _, path := range []string{"/any", "/typed"} {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+			t.Errorf("%s: expected an empty 204, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package flex
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// NotFound sets the handler of requests which match no route. The handler gets
+// the wrappers of s, like the ones of its routes. By default, the json error of
+// WrapNotFoundErr is sent. It can only be set on the root server, as the
+// router is shared with the groups.
+func (s *Server[I]) NotFound(handler Handler[I]) {
+	s.assertFallbackRoot("NotFound")
+	s.router.Router.NotFound = s.fallbackHandler(handler)
+}
+
+// MethodNotAllowed sets the handler of requests whose path matches a route but
+// not its method. The Allow header is already set when the handler is called.
+// Like NotFound, it can only be set on the root server.
+func (s *Server[I]) MethodNotAllowed(handler Handler[I]) {
+	s.assertFallbackRoot("MethodNotAllowed")
+	s.router.Router.MethodNotAllowed = s.fallbackHandler(handler)
+}
+
+// SetPanicHandler sets the handler of panics, other than a Result, raised by the
+// handlers of s and its groups. With no panic handler, the panic is propagated
+// to net/http.
+func (s *Server[I]) SetPanicHandler(handler func(i I, catch any) Result) {
+	s.panicHandler = handler
+}
+
+func (s *Server[I]) lookupPanicHandler() func(i I, catch any) Result {
+	for ; s != nil; s = s.parent {
+		if s.panicHandler != nil {
+			return s.panicHandler
+		}
+	}
+
+	return nil
+}
+
+func (s *Server[I]) assertFallbackRoot(name string) {
+	if s.parent != nil {
+		panic(name + " handler can only be set on the root server, not on group " + s.rootPath)
+	}
+}
+
+func (s *Server[I]) fallbackHandler(handler Handler[I]) http.Handler {
+	// wrapped on the first request, as wrappers are usually added after this call
+	wrapped := sync.OnceValue(func() Handler[I] {
+		h, _ := s.middleware.wrap(handler)
+		return h
+	})
+
+	route := &RouteInfo{BodyType: reflect.TypeOf(noBody)}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpRouterHandler(s, nil, r.URL.Path, r, w, route, wrapped())
+	})
+}
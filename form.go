@@ -0,0 +1,228 @@
+package flex
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/amirdlt/flex/util"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+var (
+	urlValuesType   = reflect.TypeOf(url.Values{})
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader{})
+)
+
+// formFieldName returns the form key of field from its form tag, then its json
+// tag and finally its name. It returns false if the field is skipped by "-".
+func formFieldName(field reflect.StructField) (string, bool) {
+	for _, tag := range []string{"form", "json"} {
+		if value, ok := field.Tag.Lookup(tag); ok {
+			name, _, _ := strings.Cut(value, ",")
+			if name == "-" {
+				return "", false
+			}
+
+			if name != "" {
+				return name, true
+			}
+		}
+	}
+
+	return field.Name, true
+}
+
+// decodeForm stores values in v, which must be a pointer to url.Values, a map
+// with string keys or a struct.
+func decodeForm(values url.Values, v any) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return errors.New("form can only be decoded into a non-nil pointer")
+	}
+
+	return decodeFormValue(values, nil, ptr.Elem())
+}
+
+// decodeMultipartForm is like decodeForm but also stores the files of form in
+// the *multipart.FileHeader and []*multipart.FileHeader fields of a struct.
+func decodeMultipartForm(form *multipart.Form, v any) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return errors.New("form can only be decoded into a non-nil pointer")
+	}
+
+	return decodeFormValue(form.Value, form.File, ptr.Elem())
+}
+
+func decodeFormValue(values url.Values, files map[string][]*multipart.FileHeader, v reflect.Value) error {
+	switch {
+	case v.Type() == urlValuesType:
+		v.Set(reflect.ValueOf(values))
+		return nil
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+
+		for key, raws := range values {
+			item := reflect.New(v.Type().Elem()).Elem()
+			if item.Kind() == reflect.Interface {
+				if len(raws) == 1 {
+					item.Set(reflect.ValueOf(raws[0]))
+				} else {
+					item.Set(reflect.ValueOf(raws))
+				}
+			} else if err := SetFromStrings(item, raws); err != nil {
+				return fmt.Errorf("form field %s: %w", key, err)
+			}
+
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), item)
+		}
+
+		return nil
+	case v.Kind() == reflect.Struct:
+		for index := 0; index < v.NumField(); index++ {
+			field := v.Type().Field(index)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, ok := formFieldName(field)
+			if !ok {
+				continue
+			}
+
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := decodeFormValue(values, files, v.Field(index)); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			switch field.Type {
+			case fileHeaderType:
+				if headers := files[name]; len(headers) != 0 {
+					v.Field(index).Set(reflect.ValueOf(headers[0]))
+				}
+
+				continue
+			case fileHeadersType:
+				if headers, exist := files[name]; exist {
+					v.Field(index).Set(reflect.ValueOf(headers))
+				}
+
+				continue
+			}
+
+			if raws, exist := values[name]; exist {
+				if err := SetFromStrings(v.Field(index), raws); err != nil {
+					return fmt.Errorf("form field %s: %w", name, err)
+				}
+			}
+		}
+
+		return nil
+	default:
+		return errors.New("form can not be decoded into " + v.Type().String())
+	}
+}
+
+// encodeForm converts v, a url.Values, a map with string keys or a struct, to
+// form values.
+func encodeForm(v any) (url.Values, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return url.Values{}, nil
+		}
+
+		value = value.Elem()
+	}
+
+	values := url.Values{}
+	switch {
+	case value.Type() == urlValuesType:
+		return value.Interface().(url.Values), nil
+	case value.Kind() == reflect.Map && value.Type().Key().Kind() == reflect.String:
+		iter := value.MapRange()
+		for iter.Next() {
+			addFormValue(values, iter.Key().String(), iter.Value())
+		}
+	case value.Kind() == reflect.Struct:
+		encodeFormStruct(values, value)
+	default:
+		return nil, errors.New("form can not be encoded from " + value.Type().String())
+	}
+
+	return values, nil
+}
+
+func encodeFormStruct(values url.Values, v reflect.Value) {
+	for index := 0; index < v.NumField(); index++ {
+		field := v.Type().Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, ok := formFieldName(field)
+		if !ok {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			encodeFormStruct(values, v.Field(index))
+			continue
+		}
+
+		addFormValue(values, name, v.Field(index))
+	}
+}
+
+func addFormValue(values url.Values, key string, v reflect.Value) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Array || v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		for index := 0; index < v.Len(); index++ {
+			addFormValue(values, key, v.Index(index))
+		}
+
+		return
+	}
+
+	if v.Kind() == reflect.Slice {
+		values.Add(key, string(v.Bytes()))
+	} else {
+		values.Add(key, fmt.Sprint(v.Interface()))
+	}
+}
+
+// hasFileFields reports whether the struct t has file fields, so its body is
+// sent as multipart/form-data.
+func hasFileFields(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+		if field.Type == fileHeaderType || field.Type == fileHeadersType {
+			return true
+		}
+
+		if field.Anonymous && hasFileFields(field.Type) {
+			return true
+		}
+	}
+
+	return false
+}
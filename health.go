@@ -0,0 +1,209 @@
+package flex
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const defaultHealthCheckTimeout = 5 * time.Second
+
+const (
+	HealthStatusUp           = "up"
+	HealthStatusDown         = "down"
+	HealthStatusShuttingDown = "shutting_down"
+)
+
+type HealthCheck func(ctx context.Context) error
+
+type healthCheck struct {
+	name     string
+	timeout  time.Duration
+	check    HealthCheck
+	liveness bool
+}
+
+type HealthCheckResult struct {
+	Status    string  `json:"status"`
+	Latency   string  `json:"latency"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks"`
+}
+
+// AddHealthCheck registers a readiness check. A zero timeout means 5 seconds.
+// The names of the checks must be unique, and mongo is reserved for the checks
+// of the mongo clients.
+func (s *Server[I]) AddHealthCheck(name string, timeout time.Duration, check HealthCheck) *Server[I] {
+	return s.addHealthCheck(healthCheck{name: name, timeout: timeout, check: check})
+}
+
+// AddLivenessCheck registers a liveness check, which should only fail if the
+// process must be restarted. A zero timeout means 5 seconds.
+func (s *Server[I]) AddLivenessCheck(name string, timeout time.Duration, check HealthCheck) *Server[I] {
+	return s.addHealthCheck(healthCheck{name: name, timeout: timeout, check: check, liveness: true})
+}
+
+func (s *Server[I]) addHealthCheck(check healthCheck) *Server[I] {
+	if check.timeout <= 0 {
+		check.timeout = defaultHealthCheckTimeout
+	}
+
+	root := s.root()
+	if slices.ContainsFunc(root.healthChecks, func(c healthCheck) bool { return c.name == check.name }) {
+		panic("health check already exists: " + check.name)
+	}
+
+	if check.name == "mongo" || strings.HasPrefix(check.name, "mongo:") {
+		panic("health check name is reserved for the mongo clients: " + check.name)
+	}
+
+	root.healthChecks = append(root.healthChecks, check)
+	return s
+}
+
+func (s *Server[I]) IsShuttingDown() bool {
+	return s.root().shuttingDown.Load()
+}
+
+// EnableHealthChecks serves the liveness report at path/live and the readiness
+// report, which includes a ping of every mongo client, at path/ready and path.
+// Readiness fails as soon as shutdown begins.
+func (s *Server[I]) EnableHealthChecks(path string) {
+	s.GET(path+"/live", func(i I) Result {
+		return s.healthReport(i.request().Context(), true)
+	}, noBody)
+
+	ready := func(i I) Result {
+		if s.IsShuttingDown() {
+			return Result{
+				responseBody: HealthReport{Status: HealthStatusShuttingDown, Checks: map[string]HealthCheckResult{}},
+				statusCode:   http.StatusServiceUnavailable,
+			}
+		}
+
+		return s.healthReport(i.request().Context(), false)
+	}
+
+	s.GET(path+"/ready", ready, noBody)
+	s.GET(path, ready, noBody)
+}
+
+func (s *Server[I]) readinessChecks() []healthCheck {
+	var checks []healthCheck
+	for _, check := range s.root().healthChecks {
+		if !check.liveness {
+			checks = append(checks, check)
+		}
+	}
+
+	s.mongoClientsMutex.RLock()
+	clients := maps.Clone(s.mongoClients)
+	s.mongoClientsMutex.RUnlock()
+
+	names := slices.Sorted(maps.Keys(clients))
+	for _, name := range names {
+		client := clients[name]
+		checkName := "mongo"
+		if name != "" {
+			checkName += ":" + name
+		}
+
+		checks = append(checks, healthCheck{
+			name:    checkName,
+			timeout: defaultHealthCheckTimeout,
+			check: func(ctx context.Context) error {
+				return client.Ping(ctx, nil)
+			},
+		})
+	}
+
+	return checks
+}
+
+func (s *Server[I]) healthReport(ctx context.Context, liveness bool) Result {
+	var checks []healthCheck
+	if liveness {
+		for _, check := range s.root().healthChecks {
+			if check.liveness {
+				checks = append(checks, check)
+			}
+		}
+	} else {
+		checks = s.readinessChecks()
+	}
+
+	type namedResult struct {
+		name   string
+		result HealthCheckResult
+	}
+
+	results := make(chan namedResult, len(checks))
+	for _, check := range checks {
+		go func() {
+			results <- namedResult{check.name, runHealthCheck(ctx, check)}
+		}()
+	}
+
+	report := HealthReport{Status: HealthStatusUp, Checks: map[string]HealthCheckResult{}}
+	for range checks {
+		r := <-results
+		report.Checks[r.name] = r.result
+		if r.result.Status != HealthStatusUp {
+			report.Status = HealthStatusDown
+		}
+	}
+
+	statusCode := http.StatusOK
+	if report.Status != HealthStatusUp {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	return Result{responseBody: report, statusCode: statusCode}
+}
+
+func runHealthCheck(ctx context.Context, check healthCheck) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, check.timeout)
+	defer cancel()
+
+	t := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if catch := recover(); catch != nil {
+				done <- errors.New("health check panicked")
+			}
+		}()
+
+		done <- check.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	latency := time.Since(t)
+	result := HealthCheckResult{
+		Status:    HealthStatusUp,
+		Latency:   latency.String(),
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+	}
+
+	if err != nil {
+		result.Status = HealthStatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
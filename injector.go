@@ -2,18 +2,26 @@ package flex
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"github.com/amirdlt/ffvm"
 	. "github.com/amirdlt/flex/util"
 	"github.com/julienschmidt/httprouter"
 	"io"
+	"io/fs"
+	"mime"
 	"mime/multipart"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type NoBody struct{}
@@ -27,16 +35,24 @@ type Injector interface {
 	Host() string
 	Method() string
 	ContentLength() int64
+	RequestBody() any
 	WrapOk(response any) Result
 	WrapNoContent() Result
+	WrapJsonErr(err any, code string, statusCode int) Result
 	WrapTooManyRequestsErr(err any) Result
+	WrapNotFoundErr(err any) Result
+	WrapInternalErr(err any) Result
+	WrapMethodNotAllowedErr(err any) Result
+	CheckPreconditions(etag string, lastModified time.Time)
 	SetContentType(contentType string)
 	RemoteAddr() string
 	Path() string
 	request() *http.Request
 	response() http.ResponseWriter
+	WrapResponseWriter(wrapper func(w http.ResponseWriter) http.ResponseWriter)
 	ServeStaticFile(filePath string, statusCode int) Result
 	RealIp() string
+	LogErrorf(format string, v ...any) *BasicInjector
 }
 
 type BasicInjector struct {
@@ -49,10 +65,23 @@ type BasicInjector struct {
 	defaultErrorCodes Map[int, string]
 	rawPath           string
 	logger            logger
+	loggerLevels      loggerLevel
 	ctx               context.Context
 	id                string
 	jsonHandler       JsonHandler
+	codecs            *codecRegistry
 	bodyType          reflect.Type
+	route             *RouteInfo
+	bodyValidation    bool
+	serverBodyOptions BodyOptions
+	writerClosers     []io.Closer
+	errorFormat       ErrorFormat
+}
+
+// Id returns the id of the request generated by the injector id generator of
+// the server, empty if there is none.
+func (s *BasicInjector) Id() string {
+	return s.id
 }
 
 func (s *BasicInjector) PathParameter(key string) string {
@@ -74,6 +103,13 @@ func (s *BasicInjector) readBody() {
 		s.bodyProcessed = true
 	}()
 
+	if reflect.TypeOf(noBody) == s.bodyType {
+		return
+	}
+
+	options := s.bodyOptions()
+	s.limitBody(options)
+
 	requestBodyPtr := reflect.New(s.bodyType)
 	val := reflect.ValueOf(requestBodyPtr.Elem().Interface())
 	kind := val.Kind()
@@ -81,7 +117,7 @@ func (s *BasicInjector) readBody() {
 		val.Type().Elem().Kind() == reflect.Uint8 || kind == reflect.String {
 		arr, err := io.ReadAll(s.r.Body)
 		if err != nil {
-			panic(s.WrapBadRequestErr("could not read body, err=" + err.Error()))
+			panic(s.bodyReadErr("could not read body", err))
 		}
 
 		if kind == reflect.String {
@@ -93,13 +129,91 @@ func (s *BasicInjector) readBody() {
 		return
 	}
 
-	if reflect.TypeOf(noBody) != s.bodyType {
-		if err := s.jsonHandler.NewDecoder(s.r.Body).Decode(requestBodyPtr.Interface()); err != nil {
-			panic(s.WrapBadRequestErr("could not read body as a valid json, err=" + err.Error()))
+	if s.isMultipartForm() {
+		form, err := s.MultipartForm()
+		if err != nil {
+			panic(s.bodyReadErr("could not read body as a valid "+MediaTypeMultipartForm, err))
+		}
+
+		if err := decodeMultipartForm(form, requestBodyPtr.Interface()); err != nil {
+			panic(s.WrapBadRequestErr("could not read body as a valid " + MediaTypeMultipartForm + ", err=" + err.Error()))
 		}
 
 		s.requestBody = requestBodyPtr.Elem().Interface()
+		s.validateBody(requestBodyPtr)
+		return
+	}
+
+	codec := s.requestCodec()
+	if err := decodeBody(codec, s.r.Body, requestBodyPtr.Interface(), options.DecodeOptions); err != nil {
+		panic(s.bodyReadErr("could not read body as a valid "+codec.MediaType(), err))
+	}
+
+	s.requestBody = requestBodyPtr.Elem().Interface()
+	s.validateBody(requestBodyPtr)
+}
+
+func (s *BasicInjector) isMultipartForm() bool {
+	mediaType, _, err := mime.ParseMediaType(s.GetRequestHeader("Content-Type"))
+	return err == nil && mediaType == MediaTypeMultipartForm
+}
+
+// requestCodec returns the codec of the request Content-Type. Bodies of a
+// missing or unknown content type are decoded as json, unless the route
+// restricts them by BodyOptions.ContentTypes, then it panics with 415.
+func (s *BasicInjector) requestCodec() Codec {
+	contentType := s.GetRequestHeader("Content-Type")
+	if contentType == "" || s.codecs == nil {
+		return s.defaultJsonCodec()
 	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if codec := s.codecs.lookup(mediaType); codec != nil {
+			return codec
+		}
+	}
+
+	if len(s.bodyOptions().ContentTypes) != 0 {
+		panic(s.WrapUnsupportedMediaTypeErr("unsupported content type: " + contentType))
+	}
+
+	return s.defaultJsonCodec()
+}
+
+// responseCodecs returns the codecs to marshal the response body with, in
+// order of preference. A Content-Type set by the handler is respected,
+// otherwise they are negotiated by the Accept header. It returns nothing if
+// nothing acceptable is registered.
+func (s *BasicInjector) responseCodecs() []negotiatedCodec {
+	if s.codecs == nil {
+		return []negotiatedCodec{{s.defaultJsonCodec(), MediaTypeJSON}}
+	}
+
+	if contentType := s.ResponseHeaders().Get("Content-Type"); contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			if codec := s.codecs.lookup(mediaType); codec != nil {
+				return []negotiatedCodec{{codec, contentType}}
+			}
+		}
+
+		return []negotiatedCodec{{s.defaultJsonCodec(), contentType}}
+	}
+
+	s.AddResponseHeader("Vary", "Accept")
+	return s.codecs.negotiate(s.GetRequestHeader("Accept"))
+}
+
+// defaultJsonCodec returns the registered json codec, which may be replaced by
+// RegisterCodec, or the one of the json handler.
+func (s *BasicInjector) defaultJsonCodec() Codec {
+	if s.codecs != nil {
+		if codec := s.codecs.lookup(MediaTypeJSON); codec != nil {
+			return codec
+		}
+	}
+
+	return jsonCodec{s.jsonHandler}
 }
 
 func (s *BasicInjector) Context() context.Context {
@@ -154,11 +268,10 @@ func (s *BasicInjector) Wrap(response any, statusCode int) Result {
 }
 
 func (s *BasicInjector) WrapWithContentType(response any, statusCode int, contentType string) Result {
-	s.SetResponseHeader("Content-Type", contentType)
 	return Result{
 		responseBody: response,
 		statusCode:   statusCode,
-	}
+	}.WithHeader("Content-Type", contentType)
 }
 
 func (s *BasicInjector) WrapOk(response any) Result {
@@ -171,7 +284,13 @@ func (s *BasicInjector) WrapNoContent() Result {
 	return s.Wrap(nil, http.StatusNoContent)
 }
 
+// WrapJsonErr wraps err as an error response in the error format of the server,
+// see SetErrorFormat.
 func (s *BasicInjector) WrapJsonErr(err any, code string, statusCode int) Result {
+	if s.errorFormat == ErrorFormatProblem {
+		return s.WrapProblem(problemOf(err, code, statusCode))
+	}
+
 	return s.WrapWithContentType(M{
 		"error": err,
 		"code":  code,
@@ -202,6 +321,14 @@ func (s *BasicInjector) WrapStatusNotAcceptable(err any) Result {
 	return s.WrapJsonErr(err, s.defaultErrorCodes[http.StatusNotAcceptable], http.StatusNotAcceptable)
 }
 
+func (s *BasicInjector) WrapUnsupportedMediaTypeErr(err any) Result {
+	return s.WrapJsonErr(err, s.defaultErrorCodes[http.StatusUnsupportedMediaType], http.StatusUnsupportedMediaType)
+}
+
+func (s *BasicInjector) WrapMethodNotAllowedErr(err any) Result {
+	return s.WrapJsonErr(err, s.defaultErrorCodes[http.StatusMethodNotAllowed], http.StatusMethodNotAllowed)
+}
+
 func (s *BasicInjector) WrapTooManyRequestsErr(err any) Result {
 	return s.WrapJsonErr(err, s.defaultErrorCodes[http.StatusTooManyRequests], http.StatusTooManyRequests)
 }
@@ -276,66 +403,118 @@ func (s *BasicInjector) RawPath() string {
 }
 
 func (s *BasicInjector) LogPrintln(v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogPrintLevel) {
+		return s
+	}
+
 	s.logger.println(append([]any{"path=" + s.Path()}, v...)...)
 	return s
 }
 
 func (s *BasicInjector) LogPrint(v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogPrintLevel) {
+		return s
+	}
+
 	s.logger.print(append([]any{"path=" + s.Path() + " "}, v...)...)
 	return s
 }
 
 func (s *BasicInjector) LogPrintf(format string, v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogPrintLevel) {
+		return s
+	}
+
 	s.logger.printf("path="+s.Path()+" "+format, v...)
 	return s
 }
 
 func (s *BasicInjector) LogTrace(v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogTraceLevel) {
+		return s
+	}
+
 	s.logger.println(append([]any{"[TRACE] path=" + s.Path()}, v...)...)
 	return s
 }
 
 func (s *BasicInjector) LogDebug(v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogDebugLevel) {
+		return s
+	}
+
 	s.logger.println(append([]any{"[DEBUG] path=" + s.Path()}, v...)...)
 	return s
 }
 
 func (s *BasicInjector) LogInfo(v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogInfoLevel) {
+		return s
+	}
+
 	s.logger.println(append([]any{"[INFO] path=" + s.Path()}, v...)...)
 	return s
 }
 
 func (s *BasicInjector) LogWarn(v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogWarnLevel) {
+		return s
+	}
+
 	s.logger.println(append([]any{"[WARN] path=" + s.Path()}, v...)...)
 	return s
 }
 
 func (s *BasicInjector) LogError(v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogErrorLevel) {
+		return s
+	}
+
 	s.logger.println(append([]any{"[ERROR] path=" + s.Path()}, v...)...)
 	return s
 }
 
 func (s *BasicInjector) LogTracef(format string, v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogTraceLevel) {
+		return s
+	}
+
 	s.logger.printf("[TRACE] path="+s.Path()+" "+format, v...)
 	return s
 }
 
 func (s *BasicInjector) LogDebugf(format string, v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogDebugLevel) {
+		return s
+	}
+
 	s.logger.printf("[DEBUG] path="+s.Path()+" "+format, v...)
 	return s
 }
 
 func (s *BasicInjector) LogInfof(format string, v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogInfoLevel) {
+		return s
+	}
+
 	s.logger.printf("[INFO] path="+s.Path()+" "+format, v...)
 	return s
 }
 
 func (s *BasicInjector) LogWarnf(format string, v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogWarnLevel) {
+		return s
+	}
+
 	s.logger.printf("[WARN] path="+s.Path()+" "+format, v...)
 	return s
 }
 
 func (s *BasicInjector) LogErrorf(format string, v ...any) *BasicInjector {
+	if !s.loggerLevels.isEnabledLogLevel(LogErrorLevel) {
+		return s
+	}
+
 	s.logger.printf("[ERROR] path="+s.Path()+" "+format, v...)
 	return s
 }
@@ -344,17 +523,69 @@ func (s *BasicInjector) response() http.ResponseWriter {
 	return s.w
 }
 
+// WrapResponseWriter replaces the response writer by the one returned by
+// wrapper, e.g. to compress or buffer the response. If the returned writer is
+// an io.Closer, it is closed after the response is sent, so it can flush what
+// it holds. It should implement Unwrap() http.ResponseWriter to keep
+// http.ResponseController working.
+func (s *BasicInjector) WrapResponseWriter(wrapper func(w http.ResponseWriter) http.ResponseWriter) {
+	s.w = wrapper(s.w)
+	if closer, ok := s.w.(io.Closer); ok {
+		s.writerClosers = append(s.writerClosers, closer)
+	}
+}
+
+// closeResponseWriters closes the wrapping writers, the outermost first.
+func (s *BasicInjector) closeResponseWriters() {
+	for index := len(s.writerClosers) - 1; index >= 0; index-- {
+		if err := s.writerClosers[index].Close(); err != nil {
+			s.LogPrintln("err while closing response writer, err=", err.Error())
+		}
+	}
+
+	s.writerClosers = nil
+}
+
 func (s *BasicInjector) request() *http.Request {
 	return s.r
 }
 
+// ServeStaticFile streams the file at filePath. With 200, range and
+// conditional requests are supported, other status codes send the whole file,
+// e.g. a custom 404 page.
 func (s *BasicInjector) ServeStaticFile(filePath string, statusCode int) Result {
-	file, err := os.ReadFile(filePath)
+	file, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s.WrapNotFoundErr("file not found")
+	} else if err != nil {
+		return s.WrapInternalErr("while serving static file, err=" + err.Error())
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
 	if err != nil {
 		return s.WrapInternalErr("while serving static file, err=" + err.Error())
+	} else if info.IsDir() {
+		return s.WrapNotFoundErr("file not found")
 	}
 
-	return s.Wrap(file, statusCode)
+	if statusCode == 0 || statusCode == http.StatusOK {
+		http.ServeContent(s.w, s.r, info.Name(), info.ModTime(), file)
+		return Result{terminate: true}
+	}
+
+	if contentType := mime.TypeByExtension(filepath.Ext(filePath)); contentType != "" {
+		s.SetContentType(contentType)
+	}
+
+	s.SetResponseHeader("Content-Length", strconv.FormatInt(info.Size(), 10))
+	s.w.WriteHeader(statusCode)
+	if _, err := io.Copy(s.w, file); err != nil {
+		s.LogPrintln("err while serving static file, err=", err.Error())
+	}
+
+	return Result{terminate: true}
 }
 
 func (s *BasicInjector) RequestHeader(key string) string {
@@ -464,6 +695,39 @@ func (s *BasicInjector) DefaultServeFile(filename string, statusCode int) Result
 	return s.Wrap(nil, statusCode)
 }
 
+func (s *BasicInjector) TLS() *tls.ConnectionState {
+	return s.r.TLS
+}
+
+// PeerCertificate returns the verified client certificate, or nil if the client
+// did not present one or it was not verified.
+func (s *BasicInjector) PeerCertificate() *x509.Certificate {
+	if s.r.TLS == nil || len(s.r.TLS.VerifiedChains) == 0 || len(s.r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	return s.r.TLS.VerifiedChains[0][0]
+}
+
+// PeerIdentity returns the common name of the verified client certificate.
+func (s *BasicInjector) PeerIdentity() string {
+	if cert := s.PeerCertificate(); cert != nil {
+		return cert.Subject.CommonName
+	}
+
+	return ""
+}
+
+// RequestBodyFFVM reads the body, if it is not read yet, and returns its ffvm
+// issues. With automatic validation, an invalid body is rejected already by
+// RequestBody.
 func (s *BasicInjector) RequestBodyFFVM() []ffvm.ValidatorIssue {
-	return ffvm.Validate(s.requestBody)
+	s.readBody()
+	if s.requestBody == nil {
+		return nil
+	}
+
+	body := reflect.New(reflect.TypeOf(s.requestBody))
+	body.Elem().Set(reflect.ValueOf(s.requestBody))
+	return ffvm.Validate(body.Interface())
 }
@@ -0,0 +1,65 @@
+package flex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type Hook func(ctx context.Context) error
+
+type hook struct {
+	name string
+	fn   Hook
+}
+
+type lifecycleHooks struct {
+	start    []hook
+	ready    []hook
+	shutdown []hook
+}
+
+// OnStart registers a hook which runs before the server starts serving. Start
+// hooks run in order of registration and the first failure aborts Run.
+func (s *Server[I]) OnStart(name string, fn Hook) *Server[I] {
+	root := s.root()
+	root.hooks.start = append(root.hooks.start, hook{name: name, fn: fn})
+	return s
+}
+
+// OnReady registers a hook which runs once all the listeners are accepting
+// connections. A failure shuts the server down and is returned by Run.
+func (s *Server[I]) OnReady(name string, fn Hook) *Server[I] {
+	root := s.root()
+	root.hooks.ready = append(root.hooks.ready, hook{name: name, fn: fn})
+	return s
+}
+
+// OnShutdown registers a hook which runs when shutdown begins, before in-flight
+// requests are drained. Shutdown hooks run in reverse order of registration and
+// all of them run even if some fail.
+func (s *Server[I]) OnShutdown(name string, fn Hook) *Server[I] {
+	root := s.root()
+	root.hooks.shutdown = append(root.hooks.shutdown, hook{name: name, fn: fn})
+	return s
+}
+
+func runHooks(ctx context.Context, stage string, hooks []hook, reverse, stopOnError bool) error {
+	var errs []error
+	for index := range hooks {
+		if reverse {
+			index = len(hooks) - 1 - index
+		}
+
+		if err := hooks[index].fn(ctx); err != nil {
+			err = fmt.Errorf("%s hook %s failed: %w", stage, hooks[index].name, err)
+			if stopOnError {
+				return err
+			}
+
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
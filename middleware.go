@@ -4,8 +4,11 @@ import (
 	"fmt"
 	. "github.com/amirdlt/flex/util"
 	"github.com/julienschmidt/httprouter"
+	"io"
+	"mime"
 	"net/http"
 	"reflect"
+	"strings"
 )
 
 type (
@@ -72,7 +75,23 @@ func (m *Middleware[I]) mergeMiddleware(middleware *Middleware[I]) {
 	}
 }
 
-func (m *Middleware[I]) register(method, path string, bodyType reflect.Type, specialFixedPath ...bool) {
+// wrap applies the wrappers to handler in order of priority and returns the
+// priority of each applied wrapper.
+func (m *Middleware[I]) wrap(handler Handler[I]) (Handler[I], []int) {
+	priorities := []int{}
+	m.wrappers.Items().Sort(func(i, j Item[int, []Wrapper[I]]) bool {
+		return i.Key() < j.Key()
+	}).ForEach(func(item Item[int, []Wrapper[I]]) {
+		for _, w := range item.Value() {
+			handler = w(handler)
+			priorities = append(priorities, item.Key())
+		}
+	})
+
+	return handler, priorities
+}
+
+func (m *Middleware[I]) register(method, path string, bodyType reflect.Type, specialFixedPath ...bool) *RouteInfo {
 	if len(specialFixedPath) == 0 {
 		specialFixedPath = []bool{false}
 	}
@@ -84,24 +103,28 @@ func (m *Middleware[I]) register(method, path string, bodyType reflect.Type, spe
 
 	server := m.server
 	handler := m.handler
+	route := &RouteInfo{
+		Method:           method,
+		Path:             server.rootPath + path,
+		GroupRoot:        server.rootPath,
+		BodyType:         bodyType,
+		SpecialFixedPath: specialFixedPath[0],
+	}
 
-	m.wrappers.Items().Sort(func(i, j Item[int, []Wrapper[I]]) bool {
-		return i.Key() < j.Key()
-	}).ForEach(func(item Item[int, []Wrapper[I]]) {
-		for _, w := range item.Value() {
-			handler = w(handler)
-		}
-	})
+	handler, route.WrapperPriorities = m.wrap(handler)
 
 	if specialFixedPath[0] {
 		server.router.HandleSpecialFixedPath(method, server.rootPath+path, func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-			httpRouterHandler(server, params, path, r, w, bodyType, handler)
+			httpRouterHandler(server, params, path, r, w, route, handler)
 		})
 	} else {
 		server.router.Handle(method, server.rootPath+path, func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-			httpRouterHandler(server, params, path, r, w, bodyType, handler)
+			httpRouterHandler(server, params, path, r, w, route, handler)
 		})
 	}
+
+	server.router.registry.add(route)
+	return route
 }
 
 func sendResponse(i *BasicInjector, result Result) {
@@ -113,16 +136,23 @@ func sendResponse(i *BasicInjector, result Result) {
 		result.statusCode = http.StatusOK
 	}
 
+	result.writeHeader(i.w)
+	defer result.writeTrailer(i.w)
+
+	if reader, ok := result.responseBody.(io.Reader); ok && result.stream == nil {
+		result.stream, result.responseBody = StreamReader(reader, "").stream, nil
+	}
+
+	if result.stream != nil {
+		sendStream(i, result)
+		return
+	}
+
 	if result.responseBody != nil {
 		switch result.responseBody.(type) {
 		case []byte, string, error: // ready already
 		default:
-			if marshalled, err := i.jsonHandler.Marshal(result.responseBody); err != nil {
-				result = i.WrapInternalErr("error in json marshalling, err=" + err.Error())
-				i.SetContentType("application/json")
-			} else {
-				result.responseBody = marshalled
-			}
+			result = marshalResponse(i, result)
 		}
 	}
 
@@ -140,26 +170,73 @@ func sendResponse(i *BasicInjector, result Result) {
 	result.terminate = true
 }
 
+// marshalResponse marshals the body of result with the first acceptable codec
+// which can marshal it. If there is none, the body is replaced by an error,
+// marshalled as json in the content type of the error.
+func marshalResponse(i *BasicInjector, result Result) Result {
+	codecs := i.responseCodecs()
+	if len(codecs) == 0 {
+		return marshalErrResponse(i, i.WrapStatusNotAcceptable("none of the accepted media types can be produced, accept="+i.GetRequestHeader("Accept")))
+	}
+
+	var errs []string
+	for _, c := range codecs {
+		marshalled, err := c.codec.Marshal(result.responseBody)
+		if err == nil {
+			result.responseBody = marshalled
+			i.SetContentType(c.contentType)
+			return result
+		}
+
+		errs = append(errs, c.codec.MediaType()+": "+err.Error())
+	}
+
+	return marshalErrResponse(i, i.WrapInternalErr("error in marshalling response, err="+strings.Join(errs, "; ")))
+}
+
+func marshalErrResponse(i *BasicInjector, result Result) Result {
+	result.writeHeader(i.w)
+	codec := i.defaultJsonCodec()
+	if mediaType, _, err := mime.ParseMediaType(i.ResponseHeaders().Get("Content-Type")); err == nil && i.codecs != nil {
+		if c := i.codecs.lookup(mediaType); c != nil {
+			codec = c
+		}
+	}
+
+	if marshalled, err := codec.Marshal(result.responseBody); err == nil {
+		result.responseBody = marshalled
+	} else {
+		result.responseBody, _ = i.jsonHandler.Marshal(result.responseBody)
+	}
+
+	return result
+}
+
 func httpRouterHandler[I Injector](
 	server *Server[I],
 	params httprouter.Params,
 	path string,
 	r *http.Request,
 	w http.ResponseWriter,
-	bodyType reflect.Type,
+	route *RouteInfo,
 	handler Handler[I]) {
 	baseI := server.CreateBasicInjector(path, params, r, w)
-	baseI.bodyType = bodyType
+	baseI.route = route
+	baseI.bodyType = route.BodyType
+	i := server.injector(baseI)
+	defer baseI.closeResponseWriters()
 	defer func() {
 		if catch := recover(); catch != nil {
 			if result, ok := catch.(Result); ok {
 				sendResponse(baseI, result)
+			} else if panicHandler := server.lookupPanicHandler(); panicHandler != nil {
+				sendResponse(baseI, panicHandler(i, catch))
 			} else {
 				panic(catch)
 			}
 		}
 	}()
 
-	result := handler(server.injector(baseI))
+	result := handler(i)
 	sendResponse(baseI, result)
 }
@@ -0,0 +1,377 @@
+package middleware
+
+import (
+	"bufio"
+	. "github.com/amirdlt/flex"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	EncodingZstd    = "zstd"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+const defaultCompressMinSize = 1024
+
+// DefaultCompressExcludedContentTypes are content types which are already
+// compressed, a trailing / matches a whole type, e.g. image/.
+var DefaultCompressExcludedContentTypes = []string{
+	"image/", "video/", "audio/", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/x-bzip2",
+	"application/pdf", "application/octet-stream",
+}
+
+type CompressOptions struct {
+	// Level is the compression level from 1 (fastest) to 9 (best), zero means
+	// the default of each encoding. For zstd it is mapped to the closest
+	// encoder level.
+	Level int
+	// MinSize is the min size of the body in bytes to compress, 1KiB by
+	// default. Streamed responses are compressed once they flush, regardless of
+	// their size.
+	MinSize int
+	// Encodings are the supported encodings in order of preference, zstd, gzip
+	// and deflate by default.
+	Encodings []string
+	// ExcludedContentTypes are never compressed, see
+	// DefaultCompressExcludedContentTypes which is used if it is nil.
+	ExcludedContentTypes []string
+}
+
+// Compress compresses responses with the encoding negotiated by the
+// Accept-Encoding header of the request. Responses which are small, already
+// encoded, partial or of an excluded content type are sent as is.
+func Compress[I Injector](options ...CompressOptions) Wrapper[I] {
+	var opts CompressOptions
+	switch len(options) {
+	case 0:
+	case 1:
+		opts = options[0]
+	default:
+		panic("options must be one arg at max")
+	}
+
+	if opts.MinSize <= 0 {
+		opts.MinSize = defaultCompressMinSize
+	}
+
+	if opts.Encodings == nil {
+		opts.Encodings = []string{EncodingZstd, EncodingGzip, EncodingDeflate}
+	}
+
+	for _, encoding := range opts.Encodings {
+		switch encoding {
+		case EncodingZstd, EncodingGzip, EncodingDeflate:
+		default:
+			panic("unsupported encoding: " + encoding)
+		}
+	}
+
+	if opts.Level < 0 || opts.Level > 9 {
+		panic("compression level must be between 0 and 9")
+	}
+
+	if opts.ExcludedContentTypes == nil {
+		opts.ExcludedContentTypes = DefaultCompressExcludedContentTypes
+	}
+
+	pools := map[string]*sync.Pool{}
+	for _, encoding := range opts.Encodings {
+		pools[encoding] = newCompressorPool(encoding, opts.Level)
+	}
+
+	return func(h Handler[I]) Handler[I] {
+		return func(i I) Result {
+			i.ResponseHeaders().Add("Vary", "Accept-Encoding")
+			if i.GetRequestHeader("Upgrade") != "" || i.Method() == http.MethodHead {
+				return h(i)
+			}
+
+			encoding := negotiateEncoding(i.GetRequestHeader("Accept-Encoding"), opts.Encodings)
+			if encoding == "" {
+				return h(i)
+			}
+
+			i.WrapResponseWriter(func(w http.ResponseWriter) http.ResponseWriter {
+				return &compressWriter{ResponseWriter: w, encoding: encoding, options: &opts, pool: pools[encoding]}
+			})
+
+			return h(i)
+		}
+	}
+}
+
+// negotiateEncoding returns the supported encoding with the highest quality in
+// acceptEncoding, ties are broken by the order of supported.
+func negotiateEncoding(acceptEncoding string, supported []string) string {
+	best, bestQ := "", 0.0
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+
+			q = parsed
+		}
+
+		qualities[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	for _, encoding := range supported {
+		q, exist := qualities[encoding]
+		if !exist {
+			if q, exist = qualities["*"]; !exist {
+				continue
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+// compressWriter holds the body until it reaches the min size, a flush or the
+// end of the response to decide whether to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding   string
+	options    *CompressOptions
+	pool       *sync.Pool
+	buffer     []byte
+	status     int
+	decided    bool
+	compressor compressor
+}
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+func newCompressorPool(encoding string, level int) *sync.Pool {
+	var create func() (compressor, error)
+	switch encoding {
+	case EncodingGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+
+		create = func() (compressor, error) {
+			return gzip.NewWriterLevel(nil, level)
+		}
+	case EncodingDeflate:
+		if level == 0 {
+			level = flate.DefaultCompression
+		}
+
+		create = func() (compressor, error) {
+			return zlib.NewWriterLevel(nil, level)
+		}
+	case EncodingZstd:
+		zstdLevel := zstd.SpeedDefault
+		if level != 0 {
+			zstdLevel = zstd.EncoderLevelFromZstd(level)
+		}
+
+		create = func() (compressor, error) {
+			return zstd.NewWriter(nil, zstd.WithEncoderLevel(zstdLevel), zstd.WithEncoderConcurrency(1))
+		}
+	}
+
+	if _, err := create(); err != nil {
+		panic("could not create " + encoding + " compressor, err=" + err.Error())
+	}
+
+	return &sync.Pool{New: func() any {
+		c, _ := create()
+		return c
+	}}
+}
+
+func (w *compressWriter) WriteHeader(statusCode int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	w.status = statusCode
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.decided {
+		w.buffer = append(w.buffer, p...)
+		if len(w.buffer) < w.options.MinSize {
+			return len(p), nil
+		}
+
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+
+		return len(p), nil
+	}
+
+	if w.compressor != nil {
+		return w.compressor.Write(p)
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// decide sends the header and the held body, compressed if large is true and
+// the response can be compressed.
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	// no body is sent, so an encoding set by the handler, e.g. of a
+	// precompressed file, does not apply
+	if w.status == http.StatusNotModified || w.status == http.StatusNoContent {
+		w.Header().Del("Content-Encoding")
+		w.Header().Del("Content-Length")
+	}
+
+	if large && w.compressible() {
+		header := w.Header()
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(w.buffer))
+		}
+
+		w.compressor = w.pool.Get().(compressor)
+		w.compressor.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	buffer := w.buffer
+	w.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	if w.compressor != nil {
+		_, err := w.compressor.Write(buffer)
+		return err
+	}
+
+	_, err := w.ResponseWriter.Write(buffer)
+	return err
+}
+
+func (w *compressWriter) compressible() bool {
+	header := w.Header()
+	switch {
+	case w.status < 200, w.status == http.StatusNoContent, w.status == http.StatusNotModified, w.status == http.StatusPartialContent:
+		return false
+	case header.Get("Content-Encoding") != "", header.Get("Content-Range") != "":
+		return false
+	case strings.Contains(header.Get("Cache-Control"), "no-transform"):
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(w.buffer)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return !slices.ContainsFunc(w.options.ExcludedContentTypes, func(excluded string) bool {
+		if strings.HasSuffix(excluded, "/") {
+			return strings.HasPrefix(mediaType, excluded)
+		}
+
+		return mediaType == excluded
+	})
+}
+
+// FlushError makes streamed responses compressed from their first flush.
+func (w *compressWriter) FlushError() error {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return err
+		}
+	}
+
+	if w.compressor != nil {
+		if err := w.compressor.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if w.status == 0 {
+			return nil
+		}
+
+		if err := w.decide(false); err != nil {
+			return err
+		}
+	}
+
+	if w.compressor == nil {
+		return nil
+	}
+
+	c := w.compressor
+	w.compressor = nil
+	err := c.Close()
+	w.pool.Put(c)
+	return err
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
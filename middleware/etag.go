@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"bufio"
+	. "github.com/amirdlt/flex"
+	"net"
+	"net/http"
+	"time"
+)
+
+type ETagOptions[I Injector] struct {
+	// Weak makes the computed etags weak, for responses whose bytes may change
+	// without a change of meaning, e.g. the order of map keys.
+	Weak bool
+	// Version returns the current etag and last modification time of the
+	// resource. If set, writes are checked against If-Match and
+	// If-Unmodified-Since before the handler runs, and rejected with 412.
+	Version func(i I) (etag string, lastModified time.Time)
+}
+
+// ETag computes the etag of successful GET and HEAD responses, unless the
+// handler sets one, and answers If-None-Match and If-Modified-Since (against
+// the Last-Modified header set by the handler) with 304. Streamed responses
+// are sent as is from their first flush.
+func ETag[I Injector](options ...ETagOptions[I]) Wrapper[I] {
+	var opts ETagOptions[I]
+	switch len(options) {
+	case 0:
+	case 1:
+		opts = options[0]
+	default:
+		panic("options must be one arg at max")
+	}
+
+	return func(h Handler[I]) Handler[I] {
+		return func(i I) Result {
+			if method := i.Method(); method != http.MethodGet && method != http.MethodHead {
+				if opts.Version != nil {
+					etag, lastModified := opts.Version(i)
+					i.CheckPreconditions(etag, lastModified)
+				}
+
+				return h(i)
+			}
+
+			if i.GetRequestHeader("Upgrade") != "" {
+				return h(i)
+			}
+
+			i.WrapResponseWriter(func(w http.ResponseWriter) http.ResponseWriter {
+				return &etagWriter{ResponseWriter: w, injector: i, weak: opts.Weak}
+			})
+
+			return h(i)
+		}
+	}
+}
+
+// etagWriter holds the response to compute its etag at the end.
+type etagWriter struct {
+	http.ResponseWriter
+	injector    Injector
+	weak        bool
+	buffer      []byte
+	status      int
+	passthrough bool
+}
+
+func (w *etagWriter) WriteHeader(statusCode int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	if w.status != 0 {
+		return
+	}
+
+	if statusCode >= 100 && statusCode < 200 {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	w.status = statusCode
+}
+
+func (w *etagWriter) Write(p []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	w.buffer = append(w.buffer, p...)
+	return len(p), nil
+}
+
+// send writes the held status and body.
+func (w *etagWriter) send() error {
+	w.passthrough = true
+	if w.status == 0 {
+		return nil
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	buffer := w.buffer
+	w.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	_, err := w.ResponseWriter.Write(buffer)
+	return err
+}
+
+func (w *etagWriter) FlushError() error {
+	if !w.passthrough {
+		if err := w.send(); err != nil {
+			return err
+		}
+	}
+
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *etagWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *etagWriter) Close() error {
+	if w.passthrough || w.status != http.StatusOK {
+		return w.send()
+	}
+
+	header := w.Header()
+	etag := header.Get("ETag")
+	if etag == "" {
+		if w.weak {
+			etag = WeakETag(w.buffer)
+		} else {
+			etag = StrongETag(w.buffer)
+		}
+
+		header.Set("ETag", etag)
+	}
+
+	lastModified, _ := http.ParseTime(header.Get("Last-Modified"))
+	switch Preconditions(w.injector, etag, lastModified) {
+	case http.StatusNotModified:
+		w.status, w.buffer = http.StatusNotModified, nil
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		header.Del("Content-Encoding")
+	case http.StatusPreconditionFailed:
+		w.status, w.buffer = http.StatusPreconditionFailed, nil
+		header.Del("Content-Length")
+	}
+
+	return w.send()
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
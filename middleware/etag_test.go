@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/amirdlt/flex"
+)
+
+func TestETagRevalidatesCompressed(t *testing.T) {
+	s := Default()
+	s.WrapHandler(20, ETag[*BasicInjector]())
+	s.WrapHandler(10, Compress[*BasicInjector]())
+	s.GET("/text", func(i *BasicInjector) Result {
+		i.SetContentType("text/plain")
+		return i.WrapOk(strings.Repeat("flex ", 1000))
+	}, NoBody{})
+
+	r := httptest.NewRequest(http.MethodGet, "/text", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzip response, got %d with encoding %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an etag")
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/text", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected status 304, got %d", w.Code)
+	}
+
+	for _, key := range []string{"Content-Encoding", "Content-Length", "Content-Type"} {
+		if value := w.Header().Get(key); value != "" {
+			t.Errorf("expected no %s in 304, got %q", key, value)
+		}
+	}
+
+	if w.Body.Len() != 0 {
+		t.Errorf("expected no body in 304, got %d bytes", w.Body.Len())
+	}
+}
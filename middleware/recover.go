@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	. "github.com/amirdlt/flex"
+	"runtime/debug"
+)
+
+// Recover recovers the panics of the handler, other than a Result, logs them
+// with their stack trace and responds with an internal error, in the error
+// format of the server.
+func Recover[I Injector]() Wrapper[I] {
+	return PanicHandler(func(i I, catch any) Result {
+		i.LogErrorf("panic recovered, method=%s, path=%s, panic=%v\n%s", i.Method(), i.URL().Path, catch, debug.Stack())
+		return i.WrapInternalErr("internal server error")
+	})
+}
@@ -0,0 +1,505 @@
+package flex
+
+import (
+	"encoding"
+	. "github.com/amirdlt/flex/util"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const openAPIVersion = "3.1.0"
+
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+	// SecuritySchemes are the components.securitySchemes of the document, keyed
+	// by the names used in RouteInfo.WithAuth.
+	SecuritySchemes M
+}
+
+type RouteResponse struct {
+	Description string       `json:"description,omitempty"`
+	BodyType    reflect.Type `json:"-"`
+	ContentType string       `json:"contentType,omitempty"`
+}
+
+type RouteParameter struct {
+	// In is one of query, header, path or cookie.
+	In          string       `json:"in"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Required    bool         `json:"required"`
+	Type        reflect.Type `json:"-"`
+}
+
+// WithResponse documents a response of the route. body may be nil for a
+// response with no content, or an instance of the response type.
+func (r *RouteInfo) WithResponse(statusCode int, body any, description string) *RouteInfo {
+	if r.Responses == nil {
+		r.Responses = map[int]RouteResponse{}
+	}
+
+	response := RouteResponse{Description: description, ContentType: "application/json"}
+	if body != nil {
+		response.BodyType = reflect.TypeOf(body)
+		switch body.(type) {
+		case string:
+			response.ContentType = "text/plain"
+		case []byte:
+			response.ContentType = "application/octet-stream"
+		}
+	}
+
+	r.Responses[statusCode] = response
+	return r
+}
+
+func (r *RouteInfo) WithParameter(parameter RouteParameter) *RouteInfo {
+	r.Parameters = append(r.Parameters, parameter)
+	return r
+}
+
+func (r *RouteInfo) WithOperationId(operationId string) *RouteInfo {
+	r.OperationId = operationId
+	return r
+}
+
+func (r *RouteInfo) Deprecate() *RouteInfo {
+	r.Deprecated = true
+	return r
+}
+
+// HideFromDocs excludes the route from the generated OpenAPI document.
+func (r *RouteInfo) HideFromDocs() *RouteInfo {
+	r.Hidden = true
+	return r
+}
+
+// OpenAPIDocument generates an OpenAPI 3.1 document from the registered routes.
+// Schemas are derived from the body types, honoring json tags and ffvm constraints.
+func (s *Server[_]) OpenAPIDocument(info OpenAPIInfo) M {
+	if info.Title == "" {
+		info.Title = "API"
+	}
+
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+
+	g := newSchemaGenerator()
+	paths := M{}
+	for _, route := range s.Routes() {
+		method := strings.ToLower(route.Method)
+		if route.Hidden || method == "connect" {
+			continue
+		}
+
+		path, pathParams := openAPIPath(route.Path)
+		item, exist := paths[path].(M)
+		if !exist {
+			item = M{}
+			paths[path] = item
+		}
+
+		item[method] = g.operation(route, pathParams)
+	}
+
+	docInfo := M{"title": info.Title, "version": info.Version}
+	if info.Description != "" {
+		docInfo["description"] = info.Description
+	}
+
+	doc := M{
+		"openapi": openAPIVersion,
+		"info":    docInfo,
+		"paths":   paths,
+	}
+
+	components := M{}
+	if len(g.components) != 0 {
+		components["schemas"] = g.components
+	}
+
+	if len(info.SecuritySchemes) != 0 {
+		components["securitySchemes"] = info.SecuritySchemes
+	}
+
+	if len(components) != 0 {
+		doc["components"] = components
+	}
+
+	if len(info.Servers) != 0 {
+		var servers []M
+		for _, url := range info.Servers {
+			servers = append(servers, M{"url": url})
+		}
+
+		doc["servers"] = servers
+	}
+
+	return doc
+}
+
+// ServeGeneratedOpenAPI serves the documentation UI at path and the document
+// generated from the registered routes at path/raw. The document is generated
+// on the first request, so routes registered after this call are included.
+func (s *Server[I]) ServeGeneratedOpenAPI(path string, info OpenAPIInfo) {
+	s.ServeDocs(path, DocsOptions{Generate: &info})
+}
+
+var pathParamPattern = regexp.MustCompile(`[:*]([^/]+)`)
+
+// openAPIPath converts httprouter's :name and *name segments to {name}.
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		params = append(params, match[1])
+	}
+
+	return pathParamPattern.ReplaceAllString(path, "{$1}"), params
+}
+
+var nonIdentifierPattern = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+type schemaGenerator struct {
+	components M
+	names      map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{components: M{}, names: map[reflect.Type]string{}}
+}
+
+func (g *schemaGenerator) operation(route *RouteInfo, pathParams []string) M {
+	operationId := route.OperationId
+	if operationId == "" {
+		operationId = strings.Trim(nonIdentifierPattern.ReplaceAllString(strings.ToLower(route.Method)+"_"+route.Path, "_"), "_")
+	}
+
+	op := M{"operationId": operationId}
+	if route.Summary != "" {
+		op["summary"] = route.Summary
+	}
+
+	if route.Description != "" {
+		op["description"] = route.Description
+	}
+
+	if len(route.Tags) != 0 {
+		op["tags"] = route.Tags
+	}
+
+	if route.Deprecated {
+		op["deprecated"] = true
+	}
+
+	if len(route.Auth) != 0 {
+		var security []M
+		for _, scheme := range route.Auth {
+			security = append(security, M{scheme: []string{}})
+		}
+
+		op["security"] = security
+	}
+
+	var parameters []M
+	for _, name := range pathParams {
+		documented := slices.ContainsFunc(route.Parameters, func(p RouteParameter) bool {
+			return p.In == "path" && p.Name == name
+		})
+
+		if documented {
+			continue
+		}
+
+		parameters = append(parameters, M{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   M{"type": "string"},
+		})
+	}
+
+	for _, p := range route.Parameters {
+		t := p.Type
+		if t == nil {
+			t = reflect.TypeOf("")
+		}
+
+		parameter := M{"name": p.Name, "in": p.In, "schema": g.schema(t)}
+		if p.Required || p.In == "path" {
+			parameter["required"] = true
+		}
+
+		if p.Description != "" {
+			parameter["description"] = p.Description
+		}
+
+		parameters = append(parameters, parameter)
+	}
+
+	if len(parameters) != 0 {
+		op["parameters"] = parameters
+	}
+
+	if body := g.requestBody(route.Method, route.BodyType); body != nil {
+		op["requestBody"] = body
+	}
+
+	responses := M{}
+	for statusCode, response := range route.Responses {
+		description := response.Description
+		if description == "" {
+			description = http.StatusText(statusCode)
+		}
+
+		r := M{"description": description}
+		if response.BodyType != nil {
+			r["content"] = M{response.ContentType: M{"schema": g.schema(response.BodyType)}}
+		}
+
+		responses[strconv.Itoa(statusCode)] = r
+	}
+
+	if len(responses) == 0 {
+		responses["200"] = M{"description": http.StatusText(http.StatusOK)}
+	}
+
+	op["responses"] = responses
+	return op
+}
+
+func (g *schemaGenerator) requestBody(method string, bodyType reflect.Type) M {
+	if bodyType == nil || bodyType == reflect.TypeOf(noBody) {
+		return nil
+	}
+
+	// the default body type of routes registered with no body instance
+	if bodyType == reflect.TypeOf([]byte{}) {
+		switch method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodDelete:
+			return nil
+		}
+	}
+
+	var contentType string
+	var schema M
+	switch {
+	case bodyType.Kind() == reflect.String:
+		contentType, schema = "text/plain", M{"type": "string"}
+	case (bodyType.Kind() == reflect.Slice || bodyType.Kind() == reflect.Array) && bodyType.Elem().Kind() == reflect.Uint8:
+		contentType, schema = "application/octet-stream", M{"type": "string", "format": "binary"}
+	case hasFileFields(bodyType):
+		contentType, schema = MediaTypeMultipartForm, g.schema(bodyType)
+	default:
+		contentType, schema = "application/json", g.schema(bodyType)
+	}
+
+	return M{
+		"required": true,
+		"content":  M{contentType: M{"schema": schema}},
+	}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) M {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return M{"type": "string", "format": "date-time"}
+	case reflect.TypeOf(time.Duration(0)):
+		return M{"type": "integer", "format": "int64", "description": "duration in nanoseconds"}
+	case fileHeaderType.Elem():
+		return M{"type": "string", "format": "binary"}
+	}
+
+	if t.Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()) ||
+		reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()) {
+		return M{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return M{"type": "string"}
+	case reflect.Bool:
+		return M{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return M{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return M{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return M{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return M{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return M{"type": "string", "format": "byte"}
+		}
+
+		return M{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return M{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		return M{"$ref": "#/components/schemas/" + g.componentName(t)}
+	default:
+		return M{}
+	}
+}
+
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	if name, exist := g.names[t]; exist {
+		return name
+	}
+
+	name := nonIdentifierPattern.ReplaceAllString(t.Name(), "_")
+	for _, other := range g.names {
+		if other == name {
+			name = nonIdentifierPattern.ReplaceAllString(t.PkgPath()+"."+t.Name(), "_")
+			break
+		}
+	}
+
+	// registered before generating, so recursive types refer to themselves
+	g.names[t] = name
+	g.components[name] = M{}
+	g.components[name] = g.structSchema(t)
+	return name
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) M {
+	properties := M{}
+	var required []string
+	g.addFields(t, properties, &required)
+
+	schema := M{"type": "object", "properties": properties}
+	if len(required) != 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+func (g *schemaGenerator) addFields(t reflect.Type, properties M, required *[]string) {
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+		tag, hasTag := field.Tag.Lookup("json")
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" && options == "" {
+			continue
+		}
+
+		fieldType := field.Type
+		if field.Anonymous && !hasTag {
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+
+			if fieldType.Kind() == reflect.Struct {
+				g.addFields(fieldType, properties, required)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := g.schema(field.Type)
+		isRequired := !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer
+		if constraints, ok := field.Tag.Lookup("ffvm"); ok {
+			if _, isRef := schema["$ref"]; isRef {
+				schema = M{"allOf": []M{schema}}
+			}
+
+			if applyFFVMConstraints(schema, constraints) {
+				isRequired = true
+			}
+		}
+
+		properties[name] = schema
+		if isRequired {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// applyFFVMConstraints maps the rules of an ffvm tag, e.g. `ffvm:",min_len=10;regex=^a"`,
+// to the schema and reports whether the field is required by them.
+func applyFFVMConstraints(schema M, tag string) bool {
+	_, rules, _ := strings.Cut(tag, ",")
+	required := false
+	for _, rule := range strings.Split(rules, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		number, err := strconv.ParseFloat(value, 64)
+		isNumber := err == nil
+		switch key {
+		case "required", "not_empty", "not_nil":
+			required = true
+		case "min_len":
+			if isNumber {
+				schema[lengthKey(schema, "min")] = int(number)
+			}
+		case "max_len":
+			if isNumber {
+				schema[lengthKey(schema, "max")] = int(number)
+			}
+		case "len":
+			if isNumber {
+				schema[lengthKey(schema, "min")] = int(number)
+				schema[lengthKey(schema, "max")] = int(number)
+			}
+		case "min":
+			if isNumber {
+				schema["minimum"] = number
+			}
+		case "max":
+			if isNumber {
+				schema["maximum"] = number
+			}
+		case "regex":
+			schema["pattern"] = value
+		case "enum", "in":
+			var values []string
+			for _, v := range strings.Split(value, "|") {
+				values = append(values, strings.TrimSpace(v))
+			}
+
+			schema["enum"] = values
+		case "":
+		default:
+			schema["x-ffvm-"+key] = value
+		}
+	}
+
+	return required
+}
+
+func lengthKey(schema M, prefix string) string {
+	switch schema["type"] {
+	case "array":
+		return prefix + "Items"
+	case "object":
+		return prefix + "Properties"
+	default:
+		return prefix + "Length"
+	}
+}
@@ -0,0 +1,143 @@
+package flex
+
+import (
+	"fmt"
+	. "github.com/amirdlt/flex/util"
+	"github.com/goccy/go-json"
+	"net/http"
+)
+
+// ErrorFormat is the shape of the error responses of the Wrap*Err helpers.
+type ErrorFormat string
+
+const (
+	// ErrorFormatJSON is {"error": ..., "code": ...}, the default.
+	ErrorFormatJSON ErrorFormat = "json"
+	// ErrorFormatProblem is application/problem+json of RFC 7807.
+	ErrorFormatProblem ErrorFormat = "problem"
+)
+
+const MediaTypeProblemJSON = "application/problem+json"
+
+// Problem is a problem details object of RFC 7807. Extensions are marshalled
+// as top level members, e.g. code and request_id.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions M
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members := M{}
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+
+	for k, v := range map[string]any{"type": p.Type, "title": p.Title, "detail": p.Detail, "instance": p.Instance} {
+		if v != "" {
+			members[k] = v
+		}
+	}
+
+	if p.Status != 0 {
+		members["status"] = p.Status
+	}
+
+	return json.Marshal(members)
+}
+
+// SetErrorFormat selects the shape of the error responses of s and its groups
+// which do not set their own.
+func (s *Server[I]) SetErrorFormat(format ErrorFormat) *Server[I] {
+	switch format {
+	case ErrorFormatJSON, ErrorFormatProblem:
+	default:
+		panic("unknown error format: " + string(format))
+	}
+
+	s.errorFormat = format
+	return s
+}
+
+func (s *Server[I]) lookupErrorFormat() ErrorFormat {
+	for ; s != nil; s = s.parent {
+		if s.errorFormat != "" {
+			return s.errorFormat
+		}
+	}
+
+	return ErrorFormatJSON
+}
+
+// WrapProblem responds with problem as application/problem+json. The status
+// defaults to 500, the title to the text of the status, the type to
+// about:blank and the instance to the request path. The request id is added
+// as the request_id member if there is one.
+func (s *BasicInjector) WrapProblem(problem Problem) Result {
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+
+	if problem.Instance == "" {
+		problem.Instance = s.URL().Path
+	}
+
+	if s.id != "" {
+		if problem.Extensions == nil {
+			problem.Extensions = M{}
+		}
+
+		if _, exist := problem.Extensions["request_id"]; !exist {
+			problem.Extensions["request_id"] = s.id
+		}
+	}
+
+	return s.WrapWithContentType(problem, problem.Status, MediaTypeProblemJSON)
+}
+
+// problemOf converts the err of a Wrap*Err helper to a problem, strings and
+// errors become the detail while other values, e.g. validation issues, are
+// kept as the errors member.
+func problemOf(err any, code string, statusCode int) Problem {
+	var problem Problem
+	switch value := err.(type) {
+	case Problem:
+		problem = value
+	case error:
+		problem.Detail = value.Error()
+	case string:
+		problem.Detail = value
+	case fmt.Stringer:
+		problem.Detail = value.String()
+	case nil:
+	default:
+		problem.Extensions = M{"errors": value}
+	}
+
+	if problem.Status == 0 {
+		problem.Status = statusCode
+	}
+
+	if code != "" {
+		if problem.Extensions == nil {
+			problem.Extensions = M{}
+		}
+
+		if _, exist := problem.Extensions["code"]; !exist {
+			problem.Extensions["code"] = code
+		}
+	}
+
+	return problem
+}
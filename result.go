@@ -2,12 +2,20 @@ package flex
 
 import (
 	"net/http"
+	"slices"
 )
 
+// Result is the response of a handler. It is a value, the With* methods
+// return a modified copy and leave r as is, so a Result can be stored and
+// sent more than once.
 type Result struct {
 	responseBody any
 	statusCode   int
 	terminate    bool
+	stream       *responseStream
+	header       http.Header
+	cookies      []*http.Cookie
+	trailer      http.Header
 }
 
 func (r Result) IsSuccessful() bool {
@@ -22,6 +30,10 @@ func (r Result) Body() any {
 	return r.responseBody
 }
 
+func (r Result) IsStream() bool {
+	return r.stream != nil
+}
+
 func (r Result) StatusCode() int {
 	if r.statusCode == 0 {
 		return http.StatusOK
@@ -29,3 +41,89 @@ func (r Result) StatusCode() int {
 
 	return r.statusCode
 }
+
+// Header returns a copy of the headers of r, which are set on the response
+// over the ones set by the injector.
+func (r Result) Header() http.Header {
+	return r.header.Clone()
+}
+
+func (r Result) Cookies() []*http.Cookie {
+	return slices.Clone(r.cookies)
+}
+
+func (r Result) Trailer() http.Header {
+	return r.trailer.Clone()
+}
+
+func (r Result) WithStatus(statusCode int) Result {
+	r.statusCode = statusCode
+	return r
+}
+
+func (r Result) WithBody(body any) Result {
+	r.responseBody = body
+	return r
+}
+
+// WithHeader replaces the values of the header key by values, no values
+// removes the header from the response.
+func (r Result) WithHeader(key string, values ...string) Result {
+	r.header = withHeader(r.header, key, values)
+	return r
+}
+
+func (r Result) WithCookie(cookie *http.Cookie) Result {
+	r.cookies = append(slices.Clip(r.cookies), cookie)
+	return r
+}
+
+// WithTrailer declares the trailer key, sent after the body with values.
+func (r Result) WithTrailer(key string, values ...string) Result {
+	if len(values) == 0 {
+		panic("trailer " + key + " must have a value")
+	}
+
+	r.trailer = withHeader(r.trailer, key, values)
+	return r
+}
+
+// withHeader returns a copy of header with the values of key replaced.
+func withHeader(header http.Header, key string, values []string) http.Header {
+	header = header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	header[http.CanonicalHeaderKey(key)] = slices.Clone(values)
+	return header
+}
+
+// writeHeader sets the headers and cookies of r and declares its trailers on
+// the response, before the status is written.
+func (r Result) writeHeader(w http.ResponseWriter) {
+	header := w.Header()
+	for key, values := range r.header {
+		if len(values) == 0 {
+			header.Del(key)
+		} else {
+			header[key] = slices.Clone(values)
+		}
+	}
+
+	for _, cookie := range r.cookies {
+		http.SetCookie(w, cookie)
+	}
+
+	for key := range r.trailer {
+		header.Add("Trailer", key)
+	}
+}
+
+// writeTrailer sets the trailers of r on the response, after the body.
+func (r Result) writeTrailer(w http.ResponseWriter) {
+	header := w.Header()
+	for key, values := range r.trailer {
+		header[key] = slices.Clone(values)
+	}
+}
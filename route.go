@@ -0,0 +1,163 @@
+package flex
+
+import (
+	. "github.com/amirdlt/flex/util"
+	"github.com/goccy/go-json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// RouteInfo describes a registered route. Handle and its shortcuts return it,
+// so metadata can be attached at registration, e.g.
+// s.GET("/users/:id", h).WithSummary("get a user").WithTags("user").
+type RouteInfo struct {
+	Method            string                `json:"method"`
+	Path              string                `json:"path"`
+	GroupRoot         string                `json:"groupRoot"`
+	BodyType          reflect.Type          `json:"-"`
+	WrapperPriorities []int                 `json:"wrapperPriorities"`
+	SpecialFixedPath  bool                  `json:"specialFixedPath"`
+	Summary           string                `json:"summary,omitempty"`
+	Description       string                `json:"description,omitempty"`
+	Tags              []string              `json:"tags,omitempty"`
+	Auth              []string              `json:"auth,omitempty"`
+	Metadata          M                     `json:"metadata,omitempty"`
+	OperationId       string                `json:"operationId,omitempty"`
+	Deprecated        bool                  `json:"deprecated,omitempty"`
+	Hidden            bool                  `json:"hidden,omitempty"`
+	Responses         map[int]RouteResponse `json:"responses,omitempty"`
+	Parameters        []RouteParameter      `json:"parameters,omitempty"`
+	Validation        *bool                 `json:"validation,omitempty"`
+	BodyOptions       *BodyOptions          `json:"bodyOptions,omitempty"`
+}
+
+func (r *RouteInfo) MarshalJSON() ([]byte, error) {
+	type route RouteInfo
+	body := ""
+	if r.BodyType != nil {
+		body = r.BodyType.String()
+	}
+
+	return json.Marshal(struct {
+		*route
+		Body string `json:"body"`
+	}{(*route)(r), body})
+}
+
+func (r *RouteInfo) WithSummary(summary string) *RouteInfo {
+	r.Summary = summary
+	return r
+}
+
+func (r *RouteInfo) WithDescription(description string) *RouteInfo {
+	r.Description = description
+	return r
+}
+
+func (r *RouteInfo) WithTags(tags ...string) *RouteInfo {
+	r.Tags = append(r.Tags, tags...)
+	return r
+}
+
+// WithAuth records the auth requirements of the route, e.g. the security
+// schemes it accepts. A route with no auth requirement is public.
+func (r *RouteInfo) WithAuth(requirements ...string) *RouteInfo {
+	r.Auth = append(r.Auth, requirements...)
+	return r
+}
+
+func (r *RouteInfo) WithMetadata(key string, value any) *RouteInfo {
+	if r.Metadata == nil {
+		r.Metadata = M{}
+	}
+
+	r.Metadata[key] = value
+	return r
+}
+
+func (r *RouteInfo) RequiresAuth() bool {
+	return len(r.Auth) != 0
+}
+
+type routeRegistry struct {
+	routes []*RouteInfo
+	*sync.RWMutex
+}
+
+func (r *routeRegistry) add(route *RouteInfo) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.routes = append(r.routes, route)
+}
+
+func (r *routeRegistry) all() []*RouteInfo {
+	r.RLock()
+	defer r.RUnlock()
+
+	return slices.Clone(r.routes)
+}
+
+// Routes returns the routes registered by the server and all its groups, in
+// order of registration.
+func (s *Server[_]) Routes() []*RouteInfo {
+	return s.router.RouteInfos()
+}
+
+// ServeRouteTable serves the registered routes as json, or as an html table if
+// html is accepted or requested by ?format=html.
+func (s *Server[I]) ServeRouteTable(path string) *RouteInfo {
+	return s.GET(path, func(i I) Result {
+		routes := s.Routes()
+		format := i.URL().Query().Get("format")
+		if format == "html" || format == "" && strings.Contains(i.GetRequestHeader("Accept"), "text/html") {
+			var builder strings.Builder
+			if err := routeTableTemplate.Execute(&builder, routes); err != nil {
+				i.SetContentType("text/plain")
+				return Result{responseBody: "could not render route table, err=" + err.Error(), statusCode: http.StatusInternalServerError}
+			}
+
+			i.SetContentType("text/html; charset=utf-8")
+			return i.WrapOk(builder.String())
+		}
+
+		return i.WrapOk(routes)
+	}, noBody).WithSummary("route table").WithTags("flex")
+}
+
+var routeTableTemplate = template.Must(template.New("routes").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8"/>
+    <title>Routes</title>
+    <style>
+        body { font-family: sans-serif; margin: 2em; }
+        table { border-collapse: collapse; width: 100%; }
+        th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; font-size: 14px; }
+        th { background: #f0f0f0; }
+        code { font-size: 13px; }
+    </style>
+</head>
+<body>
+<table>
+    <tr><th>Method</th><th>Path</th><th>Group</th><th>Body</th><th>Wrappers</th><th>Summary</th><th>Tags</th><th>Auth</th></tr>
+    {{- range .}}
+    <tr>
+        <td>{{.Method}}</td>
+        <td><code>{{.Path}}</code></td>
+        <td><code>{{.GroupRoot}}</code></td>
+        <td><code>{{if .BodyType}}{{.BodyType}}{{end}}</code></td>
+        <td>{{range $index, $p := .WrapperPriorities}}{{if $index}}, {{end}}{{$p}}{{end}}</td>
+        <td>{{.Summary}}</td>
+        <td>{{range $index, $t := .Tags}}{{if $index}}, {{end}}{{$t}}{{end}}</td>
+        <td>{{range $index, $a := .Auth}}{{if $index}}, {{end}}{{$a}}{{end}}</td>
+    </tr>
+    {{- end}}
+</table>
+</body>
+</html>
+`))
@@ -5,18 +5,33 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"net/http"
 	"strings"
+	"sync"
 )
 
 type Router struct {
 	apis               map[string][]string
 	specialFixedRoutes Map[string, httprouter.Handle]
+	registry           *routeRegistry
 	*httprouter.Router
 }
 
+func newRouter() Router {
+	return Router{
+		Router:             httprouter.New(),
+		apis:               map[string][]string{},
+		specialFixedRoutes: map[string]httprouter.Handle{},
+		registry:           &routeRegistry{RWMutex: &sync.RWMutex{}},
+	}
+}
+
 func (r Router) Routes() Map[string, []string] {
 	return CopyMap(r.apis)
 }
 
+func (r Router) RouteInfos() []*RouteInfo {
+	return r.registry.all()
+}
+
 func (r Router) Handle(method, path string, handle httprouter.Handle) {
 	r.apis[method] = append(r.apis[method], path)
 	r.Router.Handle(method, path, handle)
@@ -2,30 +2,44 @@ package flex
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"github.com/amirdlt/flex/db/mongo"
 	. "github.com/amirdlt/flex/util"
 	"github.com/julienschmidt/httprouter"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
+const defaultShutdownTimeout = 30 * time.Second
+
 var DefaultErrorCodes = map[int]string{
-	http.StatusBadRequest:          "ERR_BAD_REQUEST",
-	http.StatusInternalServerError: "ERR_INTERNAL_SERVER",
-	http.StatusTooManyRequests:     "ERR_TOO_MANY_REQUESTS",
-	http.StatusNotFound:            "ERR_NOT_FOUND",
-	http.StatusFound:               "ERR_ALREADY_EXIST",
-	http.StatusConflict:            "ERR_CONFLICT",
-	http.StatusForbidden:           "ERR_FORBIDDEN",
-	http.StatusNotImplemented:      "ERR_NOT_IMPLEMENTED",
-	http.StatusNotAcceptable:       "ERR_NOT_NOT_ACCEPTABLE",
+	http.StatusBadRequest:            "ERR_BAD_REQUEST",
+	http.StatusInternalServerError:   "ERR_INTERNAL_SERVER",
+	http.StatusTooManyRequests:       "ERR_TOO_MANY_REQUESTS",
+	http.StatusNotFound:              "ERR_NOT_FOUND",
+	http.StatusFound:                 "ERR_ALREADY_EXIST",
+	http.StatusConflict:              "ERR_CONFLICT",
+	http.StatusForbidden:             "ERR_FORBIDDEN",
+	http.StatusNotImplemented:        "ERR_NOT_IMPLEMENTED",
+	http.StatusNotAcceptable:         "ERR_NOT_NOT_ACCEPTABLE",
+	http.StatusMethodNotAllowed:      "ERR_METHOD_NOT_ALLOWED",
+	http.StatusUnsupportedMediaType:  "ERR_UNSUPPORTED_MEDIA_TYPE",
+	http.StatusPreconditionFailed:    "ERR_PRECONDITION_FAILED",
+	http.StatusUnprocessableEntity:   "ERR_UNPROCESSABLE_ENTITY",
+	http.StatusRequestEntityTooLarge: "ERR_REQUEST_ENTITY_TOO_LARGE",
 }
 
 type Server[I Injector] struct {
@@ -38,12 +52,34 @@ type Server[I Injector] struct {
 	injector            func(*BasicInjector) I
 	injectorIdGenerator func(*BasicInjector) string
 	mongoClients        mongo.Clients
+	mongoClientsMutex   *sync.RWMutex
 	groups              map[string]*Server[I]
 	jsonHandler         JsonHandler
+	codecs              *codecRegistry
 	middleware          *Middleware[I]
 	httpServer          *http.Server
 	startTime           time.Time
 	loggerLevels        loggerLevel
+	shutdownTimeout     time.Duration
+	shutdownDelay       time.Duration
+	cleanups            []cleanup
+	hooks               lifecycleHooks
+	healthChecks        []healthCheck
+	panicHandler        func(i I, catch any) Result
+	errorFormat         ErrorFormat
+	errorMappings       []errorMapping
+	bodyValidation      *bool
+	bodyOptions         *BodyOptions
+	production          bool
+	shuttingDown        atomic.Bool
+	webSockets          *webSocketConns
+	clientAuth          tls.ClientAuthType
+	clientCAs           *x509.CertPool
+}
+
+type cleanup struct {
+	name string
+	fn   func(ctx context.Context) error
 }
 
 type BasicServer = Server[*BasicInjector]
@@ -67,34 +103,59 @@ func New[I Injector](config M, injector func(baseInjector *BasicInjector) I) *Se
 		os.Stderr,
 	}
 
-	if loggerOut, exist := config["logger_out"]; exist {
-		if f, err := GetFileStream(loggerOut.(string)); err != nil {
-			panic(err)
-		} else {
-			logger.SetOutput(f)
-		}
-	}
+	validateConfig(config)
 
 	s := &Server[I]{
 		logger:            logger,
 		config:            config,
 		parent:            nil,
 		rootPath:          "",
-		router:            Router{Router: httprouter.New(), apis: map[string][]string{}, specialFixedRoutes: map[string]httprouter.Handle{}},
+		router:            newRouter(),
 		defaultErrorCodes: DefaultErrorCodes,
 		injector:          injector,
 		mongoClients:      mongo.Clients{},
+		mongoClientsMutex: &sync.RWMutex{},
 		groups:            map[string]*Server[I]{},
 		jsonHandler:       &DefaultJsonHandler{},
+		webSockets:        newWebSocketConns(),
 		loggerLevels: loggerLevel{
 			levels:  defaultLoggerLevels,
 			RWMutex: &sync.RWMutex{},
 		},
 	}
 
+	s.codecs = newCodecRegistry(s.jsonHandler)
 	s.middleware = newMiddleware(s)
 
-	if server, exist := s.LookupConfig("server"); exist {
+	s.NotFound(func(i I) Result {
+		return i.WrapNotFoundErr("no route found for " + i.Method() + " " + i.URL().Path)
+	})
+
+	s.MethodNotAllowed(func(i I) Result {
+		return i.WrapMethodNotAllowedErr("method " + i.Method() + " is not allowed for " + i.URL().Path)
+	})
+
+	if loggerOut := s.ConfigString(ConfigLoggerOut); loggerOut != "" {
+		if f, err := GetFileStream(loggerOut); err != nil {
+			panic(err)
+		} else {
+			s.logger.SetOutput(f)
+		}
+	}
+
+	s.shutdownTimeout = s.ConfigDuration(ConfigShutdownTimeout, defaultShutdownTimeout)
+	s.shutdownDelay = s.ConfigDuration(ConfigShutdownDelay, 0)
+	s.production = s.ConfigBool(ConfigProduction)
+
+	s.RegisterCleanup("mongo clients", func(context.Context) error {
+		s.mongoClientsMutex.Lock()
+		defer s.mongoClientsMutex.Unlock()
+
+		s.mongoClients.ClearAllClients()
+		return nil
+	})
+
+	if server, exist := s.LookupConfig(ConfigServer); exist {
 		if hs, ok := server.(*http.Server); !ok {
 			panic("expected an *http.Server, got " + fmt.Sprint(server))
 		} else if hs.Handler != nil {
@@ -107,6 +168,9 @@ func New[I Injector](config M, injector func(baseInjector *BasicInjector) I) *Se
 		s.httpServer = &http.Server{Handler: s.router}
 	}
 
+	// hijacked connections are not tracked by http.Server.Shutdown
+	s.httpServer.RegisterOnShutdown(s.webSockets.closeAll)
+
 	return s
 }
 
@@ -126,29 +190,36 @@ func (s *Server[I]) CreateBasicInjector(path string, pathParams httprouter.Param
 		defaultErrorCodes: s.defaultErrorCodes,
 		rawPath:           path,
 		logger:            s.logger,
+		loggerLevels:      s.loggerLevels,
 		ctx:               nil,
 		id:                "",
 		jsonHandler:       s.jsonHandler,
+		codecs:            s.codecs,
+		errorFormat:       s.lookupErrorFormat(),
+		bodyValidation:    s.lookupBodyValidation(),
+		serverBodyOptions: s.lookupBodyOptions(),
 	}
 
-	if s.injectorIdGenerator != nil {
-		baseI.id = s.injectorIdGenerator(baseI)
+	if idGenerator := s.lookupInjectorIdGenerator(); idGenerator != nil {
+		baseI.id = idGenerator(baseI)
 	}
 
 	return baseI
 }
 
 func (s *Server[_]) StartTime() time.Time {
-	return s.startTime
+	return s.root().startTime
 }
 
+// LookupConfig returns the value of key, which may be a dot separated path of
+// nested maps, e.g. "db.url".
 func (s *Server[_]) LookupConfig(key string) (any, bool) {
-	v, exist := s.config[key]
-	return v, exist
+	return lookupConfig(s.config, key)
 }
 
 func (s *Server[_]) Config(key string) any {
-	return s.config[key]
+	v, _ := s.LookupConfig(key)
+	return v
 }
 
 func (s *Server[_]) MongoClients() mongo.Clients {
@@ -157,6 +228,7 @@ func (s *Server[_]) MongoClients() mongo.Clients {
 
 func (s *Server[_]) SetJsonHandler(jsonHandler JsonHandler) {
 	s.jsonHandler = jsonHandler
+	s.codecs.register(jsonCodec{jsonHandler})
 }
 
 func (s *Server[_]) RootPath() string {
@@ -164,6 +236,9 @@ func (s *Server[_]) RootPath() string {
 }
 
 func (s *Server[I]) SetDefaultMongoClient(connectionUrl string) {
+	s.mongoClientsMutex.Lock()
+	defer s.mongoClientsMutex.Unlock()
+
 	if err := s.mongoClients.AddClient("", connectionUrl); err != nil {
 		panic("could not connect to mongo client: " + err.Error())
 	}
@@ -174,6 +249,9 @@ func (s *Server[I]) AddMongoClient(name, connectionUrl string) {
 		panic("mongo client name cannot be empty")
 	}
 
+	s.mongoClientsMutex.Lock()
+	defer s.mongoClientsMutex.Unlock()
+
 	if err := s.mongoClients.AddClient(name, connectionUrl); err != nil {
 		panic("could not connect to mongo client: " + err.Error())
 	}
@@ -189,6 +267,7 @@ func (s *Server[I]) Group(path string) *Server[I] {
 	} else {
 		g = &Server[I]{
 			rootPath:          s.rootPath + path,
+			config:            s.config,
 			logger:            s.logger,
 			parent:            s,
 			router:            s.router,
@@ -196,8 +275,10 @@ func (s *Server[I]) Group(path string) *Server[I] {
 			injector:          s.injector,
 			groups:            map[string]*Server[I]{},
 			mongoClients:      s.mongoClients,
+			mongoClientsMutex: s.mongoClientsMutex,
 			jsonHandler:       s.jsonHandler,
-			startTime:         s.startTime,
+			codecs:            s.codecs.clone(),
+			loggerLevels:      s.loggerLevels,
 		}
 
 		g.middleware = s.middleware.serverMiddlewareClone(g)
@@ -206,41 +287,153 @@ func (s *Server[I]) Group(path string) *Server[I] {
 	}
 }
 
+// Run listens on every given address and serves until the server is shut down.
+// An address is either "host:port", a bare port or "unix:/path/to/socket". With
+// no address, the server's configured address, then $PORT and finally :8091 is used.
 func (s *Server[_]) Run(addr ...string) error {
+	s.assertRoot()
+
+	listeners, err := s.listen(addr...)
+	if err != nil {
+		return err
+	}
+
+	return s.serve(listeners, false)
+}
+
+// Serve serves on the given listeners until the server is shut down.
+func (s *Server[_]) Serve(listeners ...net.Listener) error {
+	return s.serve(listeners, false)
+}
+
+func (s *Server[_]) serve(listeners []net.Listener, secure bool) error {
+	s.assertRoot()
+
+	if len(listeners) == 0 {
+		panic("at least one listener must be specified")
+	}
+
+	if err := runHooks(context.Background(), "start", s.hooks.start, false, true); err != nil {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+
+		return err
+	}
+
+	s.startTime = time.Now()
+
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		s.LogPrintln("server is listening on:", l.Addr().Network()+"://"+l.Addr().String())
+		go func() {
+			if secure {
+				errs <- s.httpServer.ServeTLS(l, "", "")
+			} else {
+				errs <- s.httpServer.Serve(l)
+			}
+		}()
+	}
+
+	if err := runHooks(context.Background(), "ready", s.hooks.ready, false, true); err != nil {
+		s.LogError("server is not ready, shutting down, err=", err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+		defer cancel()
+
+		err = errors.Join(err, s.Shutdown(ctx))
+		for range listeners {
+			<-errs
+		}
+
+		return err
+	}
+
+	err := <-errs
+	if !errors.Is(err, http.ErrServerClosed) {
+		// do not keep serving on a part of the addresses
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}
+
+	for range len(listeners) - 1 {
+		<-errs
+	}
+
+	return err
+}
+
+func (s *Server[_]) assertRoot() {
 	if s.parent != nil {
 		panic("only root server can be run not its children")
 	}
+}
 
-	if s.httpServer.Addr == "" {
-		var address string
-		switch len(addr) {
-		case 0:
-			if port, exist := os.LookupEnv("PORT"); exist {
-				if !strings.Contains(port, ":") {
-					address = ":" + port
-				}
-			} else {
-				address = ":8091"
+func (s *Server[_]) listen(addr ...string) ([]net.Listener, error) {
+	if len(addr) == 0 {
+		if s.httpServer.Addr != "" {
+			addr = []string{s.httpServer.Addr}
+		} else if port, exist := os.LookupEnv("PORT"); exist {
+			addr = []string{port}
+		} else {
+			addr = []string{":8091"}
+		}
+	}
+
+	listeners := make([]net.Listener, 0, len(addr))
+	for _, address := range addr {
+		l, err := listen(address)
+		if err != nil {
+			for _, opened := range listeners {
+				_ = opened.Close()
 			}
-		case 1:
-			address = addr[0]
-			if !strings.Contains(address, ":") {
-				address = ":" + address
+
+			return nil, err
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+func listen(address string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		path = strings.TrimPrefix(path, "//")
+
+		// a socket file left by a previous process which was not stopped
+		// gracefully, nobody is listening on it anymore
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			conn, err := net.Dial("unix", path)
+			if err == nil {
+				_ = conn.Close()
+				return nil, errors.New("address already in use: " + address)
+			}
+
+			if !errors.Is(err, syscall.ECONNREFUSED) {
+				return nil, err
+			}
+
+			if err := os.Remove(path); err != nil {
+				return nil, err
 			}
-		default:
-			panic("one address must be specified at max")
 		}
 
-		s.httpServer.Addr = address
+		return net.Listen("unix", path)
 	}
 
-	s.startTime = time.Now()
+	if !strings.Contains(address, ":") {
+		address = ":" + address
+	}
 
-	s.LogPrintln("server is listening on:", s.httpServer.Addr)
-	return s.httpServer.ListenAndServe()
+	return net.Listen("tcp", address)
 }
 
 func (s *Server[_]) GetMongoClient(name string) mongo.Client {
+	s.mongoClientsMutex.RLock()
+	defer s.mongoClientsMutex.RUnlock()
+
 	if c, exist := s.mongoClients[name]; exist {
 		return c
 	}
@@ -252,19 +445,86 @@ func (s *Server[_]) DefaultMongoClient() mongo.Client {
 	return s.GetMongoClient("")
 }
 
+func (s *Server[I]) root() *Server[I] {
+	for s.parent != nil {
+		s = s.parent
+	}
+
+	return s
+}
+
+// RegisterCleanup registers a function to run once the http server has stopped
+// serving. Cleanups run in reverse order of registration, so resources must be
+// registered after the ones they depend on.
+func (s *Server[I]) RegisterCleanup(name string, fn func(ctx context.Context) error) {
+	root := s.root()
+	root.cleanups = append(root.cleanups, cleanup{name: name, fn: fn})
+}
+
+// AddPeriodicJob starts the job when the server starts and stops it on shutdown.
+func (s *Server[I]) AddPeriodicJob(name string, job *PeriodicJob) {
+	s.OnStart("periodic job "+name, func(context.Context) error {
+		job.Start()
+		return nil
+	})
+
+	s.RegisterCleanup("periodic job "+name, func(context.Context) error {
+		job.Stop()
+		return nil
+	})
+}
+
+func (s *Server[I]) runCleanups(ctx context.Context) error {
+	root := s.root()
+	cleanups := root.cleanups
+	root.cleanups = nil
+
+	var errs []error
+	for index := len(cleanups) - 1; index >= 0; index-- {
+		c := cleanups[index]
+		root.LogTrace("running cleanup:", c.name)
+		if err := c.fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("cleanup %s: %w", c.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 func (s *Server[_]) Cleanup() {
-	s.mongoClients.ClearAllClients()
+	if err := s.runCleanups(context.Background()); err != nil {
+		s.LogError("cleanup failed, err=", err)
+	}
+}
+
+func (s *Server[I]) SetShutdownTimeout(timeout time.Duration) {
+	s.root().shutdownTimeout = timeout
+}
+
+func (s *Server[I]) ShutdownTimeout() time.Duration {
+	return s.root().shutdownTimeout
+}
+
+// SetShutdownDelay sets the delay between failing the readiness check of
+// EnableHealthChecks and draining on shutdown, so load balancers stop sending
+// new requests first.
+func (s *Server[I]) SetShutdownDelay(delay time.Duration) {
+	s.root().shutdownDelay = delay
+}
+
+func (s *Server[I]) ShutdownDelay() time.Duration {
+	return s.root().shutdownDelay
 }
 
 func (s *Server[_]) Router() Router {
 	return s.router
 }
 
-func (s *Server[I]) Handle(method, path string, handler any, bodyInstance ...any) {
-	s.handle(method, path, false, handler, bodyInstance...)
+func (s *Server[I]) Handle(method, path string, handler any, bodyInstance ...any) *RouteInfo {
+	return s.handle(method, path, false, handler, bodyInstance...)
 }
 
-func (s *Server[I]) handle(method, path string, specialFixedPath bool, handler any, bodyInstance ...any) {
+func (s *Server[I]) handle(method, path string, specialFixedPath bool, handler any, bodyInstance ...any) *RouteInfo {
 	if len(bodyInstance) == 0 {
 		bodyInstance = []any{[]byte{}}
 	}
@@ -275,58 +535,64 @@ func (s *Server[I]) handle(method, path string, specialFixedPath bool, handler a
 		handler = func(i I) Result { httpHandler.ServeHTTP(i.response(), i.request()); return Result{terminate: true} }
 	}
 
+	if h, ok := handler.(func(I) (any, error)); ok {
+		handler = s.errorHandler(h)
+	}
+
+	if h, ok := handler.(Handler[I]); ok {
+		handler = (func(I) Result)(h)
+	}
+
 	if h, ok := handler.(func(I) Result); ok {
 		s.middleware.handler = h
-		s.middleware.register(method, path, bodyType, specialFixedPath)
-		return
+		return s.middleware.register(method, path, bodyType, specialFixedPath)
 	} else if mid, ok := handler.(*Middleware[I]); ok {
 		m := s.middleware.serverMiddlewareClone()
 		m.mergeMiddleware(mid)
-		m.register(method, path, bodyType, specialFixedPath)
-		return
+		return m.register(method, path, bodyType, specialFixedPath)
 	}
 
 	panic("invalid type of handler: " + reflect.TypeOf(handler).String())
 }
 
-func (s *Server[I]) HandleSpecialFixedPath(method, path string, handler any, bodyInstance ...any) {
-	s.handle(method, path, true, handler, bodyInstance...)
+func (s *Server[I]) HandleSpecialFixedPath(method, path string, handler any, bodyInstance ...any) *RouteInfo {
+	return s.handle(method, path, true, handler, bodyInstance...)
 }
 
-func (s *Server[_]) POST(path string, handler any, bodyInstance ...any) {
-	s.Handle(http.MethodPost, path, handler, bodyInstance...)
+func (s *Server[_]) POST(path string, handler any, bodyInstance ...any) *RouteInfo {
+	return s.Handle(http.MethodPost, path, handler, bodyInstance...)
 }
 
-func (s *Server[_]) GET(path string, handler any, bodyInstance ...any) {
-	s.Handle(http.MethodGet, path, handler, bodyInstance...)
+func (s *Server[_]) GET(path string, handler any, bodyInstance ...any) *RouteInfo {
+	return s.Handle(http.MethodGet, path, handler, bodyInstance...)
 }
 
-func (s *Server[_]) PUT(path string, handler any, bodyInstance ...any) {
-	s.Handle(http.MethodPut, path, handler, bodyInstance...)
+func (s *Server[_]) PUT(path string, handler any, bodyInstance ...any) *RouteInfo {
+	return s.Handle(http.MethodPut, path, handler, bodyInstance...)
 }
 
-func (s *Server[_]) DELETE(path string, handler any, bodyInstance ...any) {
-	s.Handle(http.MethodDelete, path, handler, bodyInstance...)
+func (s *Server[_]) DELETE(path string, handler any, bodyInstance ...any) *RouteInfo {
+	return s.Handle(http.MethodDelete, path, handler, bodyInstance...)
 }
 
-func (s *Server[_]) OPTIONS(path string, handler any, bodyInstance ...any) {
-	s.Handle(http.MethodOptions, path, handler, bodyInstance...)
+func (s *Server[_]) OPTIONS(path string, handler any, bodyInstance ...any) *RouteInfo {
+	return s.Handle(http.MethodOptions, path, handler, bodyInstance...)
 }
 
-func (s *Server[_]) HEAD(path string, handler any, bodyInstance ...any) {
-	s.Handle(http.MethodHead, path, handler, bodyInstance...)
+func (s *Server[_]) HEAD(path string, handler any, bodyInstance ...any) *RouteInfo {
+	return s.Handle(http.MethodHead, path, handler, bodyInstance...)
 }
 
-func (s *Server[_]) PATCH(path string, handler any, bodyInstance ...any) {
-	s.Handle(http.MethodPatch, path, handler, bodyInstance...)
+func (s *Server[_]) PATCH(path string, handler any, bodyInstance ...any) *RouteInfo {
+	return s.Handle(http.MethodPatch, path, handler, bodyInstance...)
 }
 
-func (s *Server[_]) CONNECT(path string, handler any, bodyInstance ...any) {
-	s.Handle(http.MethodConnect, path, handler, bodyInstance...)
+func (s *Server[_]) CONNECT(path string, handler any, bodyInstance ...any) *RouteInfo {
+	return s.Handle(http.MethodConnect, path, handler, bodyInstance...)
 }
 
-func (s *Server[_]) TRACE(path string, handler any, bodyInstance ...any) {
-	s.Handle(http.MethodTrace, path, handler, bodyInstance...)
+func (s *Server[_]) TRACE(path string, handler any, bodyInstance ...any) *RouteInfo {
+	return s.Handle(http.MethodTrace, path, handler, bodyInstance...)
 }
 
 func (s *Server[I]) WrapHandler(priority int, wrapper Wrapper[I]) *Server[I] {
@@ -334,12 +600,11 @@ func (s *Server[I]) WrapHandler(priority int, wrapper Wrapper[I]) *Server[I] {
 	return s
 }
 
-func (s *Server[I]) FileServer(path, root string) {
-	fs := http.FileServer(http.Dir(root))
-	s.GET(path, func(i I) Result {
-		fs.ServeHTTP(i.response(), i.request())
-		return Result{terminate: true}
-	}, NoBody{})
+// FileServer serves the files of the directory root at the paths of the
+// requests matching path, e.g. /files/*filepath, with directory listing. See
+// Static for the other options.
+func (s *Server[I]) FileServer(path, root string) *RouteInfo {
+	return s.GET(path, StaticHandler[I](os.DirFS(root), StaticOptions{Browse: true}), NoBody{})
 }
 
 func (s *Server[I]) IsEnabledLogLevel(level string) bool {
@@ -495,8 +760,12 @@ func (s *Server[I]) SetLoggerOutput(w io.Writer) {
 	s.logger.SetOutput(w)
 }
 
+// Shutdown reports not ready, waits for the shutdown delay, runs the shutdown
+// hooks, stops accepting new connections, waits for in-flight requests until
+// ctx is done and then runs the registered cleanups.
 func (s *Server[I]) Shutdown(ctx context.Context) (err error) {
-	if s.httpServer == nil {
+	root := s.root()
+	if root.httpServer == nil {
 		return nil
 	}
 
@@ -504,19 +773,88 @@ func (s *Server[I]) Shutdown(ctx context.Context) (err error) {
 		ctx = context.Background()
 	}
 
-	s.mongoClients.ClearAllClients()
-	s.startTime = time.Time{}
-
 	defer func() {
 		s.LogTrace("**********", "server shutdown, err=", err, "**********")
 	}()
 
-	err = s.httpServer.Shutdown(ctx)
+	root.shuttingDown.Store(true)
+	if root.shutdownDelay > 0 {
+		s.LogInfo("reported not ready, waiting before draining, delay=", root.shutdownDelay)
+
+		timer := time.NewTimer(root.shutdownDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	err = runHooks(ctx, "shutdown", root.hooks.shutdown, true, false)
+	err = errors.Join(err, root.httpServer.Shutdown(ctx))
+	root.startTime = time.Time{}
+
+	cleanupCtx := ctx
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		cleanupCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), root.shutdownTimeout)
+		defer cancel()
+	}
+
+	err = errors.Join(err, root.runCleanups(cleanupCtx))
 	return
 }
 
+// UntilSignal calls run, e.g. s.Run, and blocks until it returns or one of the
+// signals (SIGINT and SIGTERM by default) is received. On a signal the server is
+// shut down gracefully, bounded by the shutdown timeout.
+func (s *Server[I]) UntilSignal(run func() error, signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), signals...)
+	defer stop()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- run()
+	}()
+
+	select {
+	case err := <-runErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+
+		return errors.Join(err, s.runCleanups(context.Background()))
+	case <-ctx.Done():
+	}
+
+	// restore default behaviour, so a second signal terminates immediately
+	stop()
+
+	root := s.root()
+	s.LogInfo("shutdown signal received, draining in-flight requests, timeout=", root.shutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), root.shutdownDelay+root.shutdownTimeout)
+	defer cancel()
+
+	err := s.Shutdown(shutdownCtx)
+	if rErr := <-runErr; rErr != nil && !errors.Is(rErr, http.ErrServerClosed) {
+		err = errors.Join(rErr, err)
+	}
+
+	return err
+}
+
+func (s *Server[I]) RunUntilSignal(addr ...string) error {
+	return s.UntilSignal(func() error {
+		return s.Run(addr...)
+	})
+}
+
 func (s *Server[I]) IsListening() bool {
-	return s.startTime == time.Time{}
+	return !s.root().startTime.IsZero()
 }
 
 func (s *Server[I]) ServeOpenAPI(path, indexFilePath, rawDocFilePath string) {
@@ -536,71 +874,22 @@ func (s *Server[I]) ServeOpenAPI(path, indexFilePath, rawDocFilePath string) {
 	}, noBody)
 }
 
+// ServeDefaultOpenAPI serves the embedded documentation UI at path for the
+// document at rawDocFilePath, see ServeDocs.
 func (s *Server[I]) ServeDefaultOpenAPI(path, rawDocFilePath string) {
-	s.GET(path, func(i I) Result {
-		i.SetContentType("text/html")
-		return i.WrapOk([]byte(`<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="utf-8"/>
-    <meta name="viewport" content="width=device-width, initial-scale=1"/>
-    <title>API Documentation</title>
-    <link rel="preconnect" href="https://fonts.googleapis.com"/>
-    <link rel="preconnect" href="https://fonts.gstatic.com" crossorigin/>
-    <link
-            href="https://fonts.googleapis.com/css2?family=IBM+Plex+Mono:ital,wght@0,100;0,200;0,300;0,400;0,500;0,600;0,700;1,100;1,200;1,300;1,400;1,500;1,600;1,700&display=swap"
-            rel="stylesheet"
-    />
-    <link
-            href="https://fonts.googleapis.com/css2?family=Plus+Jakarta+Sans:ital,wght@0,200;0,300;0,400;0,500;0,600;0,700;0,800;1,200;1,300;1,400;1,500;1,600;1,700;1,800&display=swap"
-            rel="stylesheet"
-    />
-    <script
-            type="module"
-            src="https://cdn.jsdelivr.net/npm/rapidoc@9.2.0/dist/rapidoc-min.js"
-            integrity="sha256-zKHbtf55GvlWwNiTYfoDmiXInEyFLp08JnhU8Gmv49k="
-            crossorigin="anonymous"
-    ></script>
-</head>
-<body>
-<rapi-doc
-        spec-url="` + path + `/raw"
-		show-header="false"
-        id="thedoc"
-        theme = "dark"
-        render-style="view"
-        schema-style="table"
-        show-method-in-nav-bar = "true"
-        use-path-in-nav-bar = "true"
-        show-components = "true"
-        show-info = "true"
-        show-header = "false"
-        allow-search = "false"
-        allow-advanced-search = "true"
-        allow-spec-url-load="false"
-        allow-spec-file-download="false"
-        allow-server-selection = "true"
-        allow-authentication	= "true"
-        update-route="false"
-        match-type="regex"
-        persist-auth="true"
-></rapi-doc>
-</body>
-</html>
-`))
-	}, noBody)
-
-	s.GET(path+"/raw", func(i I) Result {
-		contentType := "text/yaml"
-		if strings.HasSuffix(rawDocFilePath, ".json") {
-			contentType = "application/json"
-		}
-
-		i.SetContentType(contentType)
-		return i.ServeStaticFile(rawDocFilePath, http.StatusOK)
-	}, noBody)
+	s.ServeDocs(path, DocsOptions{SpecFile: rawDocFilePath})
 }
 
 func (s *Server[I]) SetInjectorIdGenerator(injectorIdGenerator func(*BasicInjector) string) {
 	s.injectorIdGenerator = injectorIdGenerator
 }
+
+func (s *Server[I]) lookupInjectorIdGenerator() func(*BasicInjector) string {
+	for ; s != nil; s = s.parent {
+		if s.injectorIdGenerator != nil {
+			return s.injectorIdGenerator
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package flex
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultSSEHeartbeat = 15 * time.Second
+
+// SSEEvent is a server-sent event, only Data is required. Data is sent as is
+// if it is a string or []byte and as json otherwise.
+type SSEEvent struct {
+	Id    string
+	Event string
+	Data  any
+	Retry time.Duration
+}
+
+// SSEStream sends events to a client connected by ServeSSE. It is safe for
+// concurrent use.
+type SSEStream struct {
+	i           *BasicInjector
+	controller  *http.ResponseController
+	ctx         context.Context
+	lastEventId string
+	*sync.Mutex
+}
+
+func (s *SSEStream) Send(event SSEEvent) error {
+	if strings.ContainsAny(event.Id, "\r\n\x00") || strings.ContainsAny(event.Event, "\r\n") {
+		return errors.New("sse event id and type can not contain new lines")
+	}
+
+	var data []byte
+	switch value := event.Data.(type) {
+	case nil:
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		marshalled, err := s.i.jsonHandler.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		data = marshalled
+	}
+
+	var buffer bytes.Buffer
+	if event.Id != "" {
+		buffer.WriteString("id: " + event.Id + "\n")
+	}
+
+	if event.Event != "" {
+		buffer.WriteString("event: " + event.Event + "\n")
+	}
+
+	if event.Retry > 0 {
+		buffer.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		buffer.WriteString("data: " + line + "\n")
+	}
+
+	buffer.WriteString("\n")
+	return s.write(buffer.Bytes())
+}
+
+// SendData sends an event with only data.
+func (s *SSEStream) SendData(data any) error {
+	return s.Send(SSEEvent{Data: data})
+}
+
+// Comment sends a comment line, which clients ignore. It is used as heartbeat
+// to keep the connection open through proxies.
+func (s *SSEStream) Comment(text string) error {
+	return s.write([]byte(": " + strings.ReplaceAll(text, "\n", " ") + "\n\n"))
+}
+
+func (s *SSEStream) write(data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.i.w.Write(data); err != nil {
+		return err
+	}
+
+	if err := s.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
+
+// Context is done when the client disconnects.
+func (s *SSEStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// LastEventId returns the id of the last event the client received before
+// reconnecting, empty on the first connection.
+func (s *SSEStream) LastEventId() string {
+	return s.lastEventId
+}
+
+// LastEventID returns the Last-Event-ID header sent by reconnecting clients.
+func (s *BasicInjector) LastEventID() string {
+	return s.GetRequestHeader("Last-Event-ID")
+}
+
+// ServeSSE opens a server-sent events stream and calls fn to send events until
+// it returns or the client disconnects. A heartbeat comment is sent every
+// heartbeat (15s by default, non-positive disables it). The returned Result
+// is terminating, so the handler should return it as is.
+func (s *BasicInjector) ServeSSE(fn func(stream *SSEStream) error, heartbeat ...time.Duration) Result {
+	interval := defaultSSEHeartbeat
+	if len(heartbeat) != 0 {
+		interval = heartbeat[0]
+	}
+
+	ctx, cancel := context.WithCancel(s.Context())
+	stream := &SSEStream{
+		i:           s,
+		controller:  http.NewResponseController(s.w),
+		ctx:         ctx,
+		lastEventId: s.LastEventID(),
+		Mutex:       &sync.Mutex{},
+	}
+
+	// nothing can be written after the handler returns, so wait for a write
+	// in progress and make the later ones fail
+	defer func() {
+		cancel()
+		stream.Lock()
+		stream.Unlock()
+	}()
+
+	headers := s.ResponseHeaders()
+	headers.Set("Content-Type", "text/event-stream")
+	headers.Set("Cache-Control", "no-cache")
+	headers.Set("X-Accel-Buffering", "no")
+	headers.Del("Content-Length")
+	s.w.WriteHeader(http.StatusOK)
+	if err := stream.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.LogPrintln("err while opening sse stream, err=", err.Error())
+		return Result{terminate: true}
+	}
+
+	if interval > 0 {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := stream.Comment("heartbeat"); err != nil {
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	if err := fn(stream); err != nil && ctx.Err() == nil {
+		s.LogPrintln("err in sse stream, err=", err.Error())
+	}
+
+	return Result{terminate: true}
+}
@@ -0,0 +1,387 @@
+package flex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ImmutableCacheControl is the Cache-Control of fingerprinted files, whose
+// content never changes under the same name.
+const ImmutableCacheControl = "public, max-age=31536000, immutable"
+
+const fingerprintLength = 16
+
+var fingerprintPattern = regexp.MustCompile(`^(.+)\.([0-9a-f]{16})((?:\.[^./]+)?)$`)
+
+type StaticOptions struct {
+	// Index is the file served for a directory, index.html by default.
+	Index string
+	// Browse lists the files of the directories which have no index.
+	Browse bool
+	// SPAFallback serves the index of the root for the paths which are not
+	// found and have no extension, for the client side routes of a single
+	// page app.
+	SPAFallback bool
+	// Precompressed serves the .zst or .gz sibling of a file, if the client
+	// accepts it, instead of compressing on the fly.
+	Precompressed bool
+	// CacheControl is the Cache-Control of the files which are not
+	// fingerprinted, no-cache by default so clients revalidate with the etag.
+	CacheControl string
+	// Fingerprint serves every file also under its fingerprinted name, see
+	// FingerprintedName, with ImmutableCacheControl.
+	Fingerprint bool
+	// Immutable reports whether name is fingerprinted by a build tool, e.g.
+	// assets/app.3f2a9c1b.js, to be served with ImmutableCacheControl.
+	Immutable func(name string) bool
+}
+
+// Static serves the files of fsys, e.g. an embed.FS or os.DirFS, under path.
+// Range requests and conditional requests are supported, the etags are the
+// hashes of the files.
+func (s *Server[I]) Static(path string, fsys fs.FS, options ...StaticOptions) *RouteInfo {
+	prefix := s.rootPath + strings.TrimSuffix(path, "/")
+	files := newStaticFiles(fsys, options)
+	return s.GET(strings.TrimSuffix(path, "/")+"/*filepath", func(i I) Result {
+		return files.serve(i, strings.TrimPrefix(i.URL().Path, prefix))
+	}, NoBody{})
+}
+
+// StaticHandler serves the file of fsys at the path of the request, e.g. to
+// serve a single page app at the root with s.NotFound(StaticHandler(...)).
+func StaticHandler[I Injector](fsys fs.FS, options ...StaticOptions) Handler[I] {
+	files := newStaticFiles(fsys, options)
+	return func(i I) Result {
+		return files.serve(i, i.URL().Path)
+	}
+}
+
+// FingerprintedName returns name with the hash of its content before its
+// extension, e.g. app.js becomes app.0123456789abcdef.js, which is served by
+// Static if the Fingerprint option is set.
+func FingerprintedName(fsys fs.FS, name string) (string, error) {
+	hash, err := hashStaticFile(fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash[:fingerprintLength] + ext, nil
+}
+
+type staticFiles struct {
+	fsys    fs.FS
+	options StaticOptions
+	hashes  map[string]staticHash
+	*sync.Mutex
+}
+
+type staticHash struct {
+	modTime time.Time
+	size    int64
+	hash    string
+}
+
+func newStaticFiles(fsys fs.FS, options []StaticOptions) *staticFiles {
+	if fsys == nil {
+		panic("static file system can not be nil")
+	}
+
+	var opts StaticOptions
+	switch len(options) {
+	case 0:
+	case 1:
+		opts = options[0]
+	default:
+		panic("options must be one arg at max")
+	}
+
+	if opts.Index == "" {
+		opts.Index = "index.html"
+	}
+
+	if opts.CacheControl == "" {
+		opts.CacheControl = "no-cache"
+	}
+
+	return &staticFiles{fsys: fsys, options: opts, hashes: map[string]staticHash{}, Mutex: &sync.Mutex{}}
+}
+
+func (f *staticFiles) serve(i Injector, urlPath string) Result {
+	if method := i.Method(); method != http.MethodGet && method != http.MethodHead {
+		return i.WrapNotFoundErr("file not found")
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		name = "."
+	}
+
+	if !fs.ValidPath(name) {
+		return i.WrapNotFoundErr("file not found")
+	}
+
+	cacheControl := f.options.CacheControl
+	if f.options.Immutable != nil && f.options.Immutable(name) {
+		cacheControl = ImmutableCacheControl
+	}
+
+	info, err := fs.Stat(f.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) && f.options.Fingerprint {
+		if original, ok := f.unfingerprint(name); ok {
+			name, cacheControl = original, ImmutableCacheControl
+			info, err = fs.Stat(f.fsys, name)
+		}
+	}
+
+	if errors.Is(err, fs.ErrNotExist) && f.options.SPAFallback && path.Ext(name) == "" {
+		name, cacheControl = f.options.Index, f.options.CacheControl
+		info, err = fs.Stat(f.fsys, name)
+	}
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return i.WrapNotFoundErr("file not found")
+	} else if err != nil {
+		return i.WrapInternalErr("while serving static file, err=" + err.Error())
+	}
+
+	if info.IsDir() {
+		if !strings.HasSuffix(i.URL().Path, "/") {
+			http.Redirect(i.response(), i.request(), path.Base(i.URL().Path)+"/", http.StatusMovedPermanently)
+			return Result{terminate: true}
+		}
+
+		index := path.Join(name, f.options.Index)
+		if indexInfo, err := fs.Stat(f.fsys, index); err == nil && !indexInfo.IsDir() {
+			name, info = index, indexInfo
+		} else if f.options.Browse {
+			return f.list(i, name)
+		} else {
+			return i.WrapNotFoundErr("file not found")
+		}
+	}
+
+	return f.serveFile(i, name, info, cacheControl)
+}
+
+// unfingerprint returns the name of the file whose fingerprinted name is name.
+func (f *staticFiles) unfingerprint(name string) (string, bool) {
+	match := fingerprintPattern.FindStringSubmatch(name)
+	if match == nil {
+		return "", false
+	}
+
+	original := match[1] + match[3]
+	hash, err := f.hash(original)
+	if err != nil || hash[:fingerprintLength] != match[2] {
+		return "", false
+	}
+
+	return original, true
+}
+
+func (f *staticFiles) serveFile(i Injector, name string, info fs.FileInfo, cacheControl string) Result {
+	header := i.ResponseHeaders()
+	// without a known extension, ServeContent sniffs the content type
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", cacheControl)
+	}
+
+	if f.options.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		acceptEncoding := i.GetRequestHeader("Accept-Encoding")
+		for _, encoding := range []struct{ name, ext string }{{"zstd", ".zst"}, {"gzip", ".gz"}} {
+			if !acceptsEncoding(acceptEncoding, encoding.name) {
+				continue
+			}
+
+			if compressedInfo, err := fs.Stat(f.fsys, name+encoding.ext); err == nil && !compressedInfo.IsDir() {
+				if header.Get("Content-Type") == "" {
+					header.Set("Content-Type", "application/octet-stream")
+				}
+
+				header.Set("Content-Encoding", encoding.name)
+				name, info = name+encoding.ext, compressedInfo
+				break
+			}
+		}
+	}
+
+	hash, err := f.hash(name)
+	if err != nil {
+		return i.WrapInternalErr("while serving static file, err=" + err.Error())
+	}
+
+	header.Set("ETag", `"`+hash[:32]+`"`)
+
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return i.WrapInternalErr("while serving static file, err=" + err.Error())
+	}
+
+	defer file.Close()
+
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return i.WrapInternalErr("while serving static file, err=" + err.Error())
+		}
+
+		content = bytes.NewReader(data)
+	}
+
+	http.ServeContent(i.response(), i.request(), name, info.ModTime(), content)
+	return Result{terminate: true}
+}
+
+// hash returns the hash of the file, cached until its size or modification
+// time changes.
+func (f *staticFiles) hash(name string) (string, error) {
+	info, err := fs.Stat(f.fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	f.Lock()
+	cached, exist := f.hashes[name]
+	f.Unlock()
+	if exist && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.hash, nil
+	}
+
+	hash, err := hashStaticFile(f.fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	f.Lock()
+	f.hashes[name] = staticHash{info.ModTime(), info.Size(), hash}
+	f.Unlock()
+	return hash, nil
+}
+
+func hashStaticFile(fsys fs.FS, name string) (string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+var staticListTemplate = template.Must(template.New("list").Parse(`<!doctype html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<ul>
+{{range .Entries}}<li><a href="{{.Href}}">{{.Name}}</a></li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+type staticListEntry struct {
+	Name string
+	Href string
+}
+
+// list responds with the entries of the directory name, directories first.
+func (f *staticFiles) list(i Injector, name string) Result {
+	entries, err := fs.ReadDir(f.fsys, name)
+	if err != nil {
+		return i.WrapInternalErr("while listing directory, err=" + err.Error())
+	}
+
+	slices.SortStableFunc(entries, func(a, b fs.DirEntry) int {
+		if a.IsDir() != b.IsDir() {
+			if a.IsDir() {
+				return -1
+			}
+
+			return 1
+		}
+
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	page := struct {
+		Title   string
+		Entries []staticListEntry
+	}{Title: i.URL().Path}
+
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+
+		// ./ keeps names with a colon from being read as a scheme
+		href := (&url.URL{Path: "./" + entryName}).String()
+		page.Entries = append(page.Entries, staticListEntry{entryName, href})
+	}
+
+	var buffer bytes.Buffer
+	if err := staticListTemplate.Execute(&buffer, page); err != nil {
+		return i.WrapInternalErr("while listing directory, err=" + err.Error())
+	}
+
+	i.SetContentType("text/html; charset=utf-8")
+	i.ResponseHeaders().Set("Cache-Control", "no-cache")
+	return i.WrapOk(buffer.Bytes())
+}
+
+// acceptsEncoding reports whether encoding has a non zero quality in
+// acceptEncoding, directly or by *.
+func acceptsEncoding(acceptEncoding, encoding string) bool {
+	accepted := false
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != encoding && name != "*" {
+			continue
+		}
+
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+
+		if name == encoding {
+			return q > 0
+		}
+
+		accepted = q > 0
+	}
+
+	return accepted
+}
@@ -0,0 +1,230 @@
+package flex
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+)
+
+type StreamFormat string
+
+const (
+	// StreamRaw writes items back to back with no framing.
+	StreamRaw StreamFormat = ""
+	// StreamJSONArray writes items as the elements of a json array. The
+	// closing bracket is not written if the stream fails, so clients can tell
+	// a truncated response from a complete one.
+	StreamJSONArray StreamFormat = "json_array"
+	// StreamNDJSON writes each item as json followed by a new line.
+	StreamNDJSON StreamFormat = "ndjson"
+)
+
+const streamBufferSize = 32 * 1024
+
+type responseStream struct {
+	format      StreamFormat
+	contentType string
+	write       func(w *StreamWriter) error
+}
+
+// StreamWriter writes a streamed response. The response is sent with chunked
+// transfer encoding, each Flush sends the written data to the client.
+type StreamWriter struct {
+	w           io.Writer
+	controller  *http.ResponseController
+	jsonHandler JsonHandler
+	format      StreamFormat
+	items       int
+}
+
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// WriteItem marshals item as json and writes it framed by the stream format.
+func (w *StreamWriter) WriteItem(item any) error {
+	var data []byte
+	switch value := item.(type) {
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		marshalled, err := w.jsonHandler.Marshal(item)
+		if err != nil {
+			return err
+		}
+
+		data = marshalled
+	}
+
+	switch w.format {
+	case StreamJSONArray:
+		separator := ","
+		if w.items == 0 {
+			separator = "["
+		}
+
+		data = append([]byte(separator), data...)
+	case StreamNDJSON:
+		data = append(data, '\n')
+	}
+
+	if _, err := w.w.Write(data); err != nil {
+		return err
+	}
+
+	w.items++
+	return nil
+}
+
+func (w *StreamWriter) Flush() error {
+	if err := w.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
+
+// Items returns the number of items written by WriteItem.
+func (w *StreamWriter) Items() int {
+	return w.items
+}
+
+func (w *StreamWriter) end() error {
+	if w.format == StreamJSONArray {
+		closing := "]"
+		if w.items == 0 {
+			closing = "[]"
+		}
+
+		if _, err := io.WriteString(w.w, closing); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func streamContentType(format StreamFormat) string {
+	switch format {
+	case StreamJSONArray:
+		return "application/json"
+	case StreamNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// StreamFunc streams the response written by fn, the content type is set by
+// the format unless it was set already.
+func StreamFunc(format StreamFormat, fn func(w *StreamWriter) error) Result {
+	return Result{stream: &responseStream{format: format, contentType: streamContentType(format), write: fn}}
+}
+
+// StreamReader streams reader to the client, flushing after each read. It is
+// closed at the end if it is an io.Closer.
+func StreamReader(reader io.Reader, contentType string) Result {
+	if contentType == "" {
+		contentType = streamContentType(StreamRaw)
+	}
+
+	return Result{stream: &responseStream{contentType: contentType, write: func(w *StreamWriter) error {
+		if closer, ok := reader.(io.Closer); ok {
+			defer closer.Close()
+		}
+
+		buffer := make([]byte, streamBufferSize)
+		for {
+			n, err := reader.Read(buffer)
+			if n > 0 {
+				if _, err := w.Write(buffer[:n]); err != nil {
+					return err
+				}
+
+				if err := w.Flush(); err != nil {
+					return err
+				}
+			}
+
+			if errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	}}}
+}
+
+// StreamSeq streams the items of seq, flushing after each one.
+func StreamSeq[T any](seq iter.Seq[T], format StreamFormat) Result {
+	return StreamFunc(format, func(w *StreamWriter) error {
+		for item := range seq {
+			if err := w.WriteItem(item); err != nil {
+				return err
+			}
+
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// StreamSeq2 streams the items of seq, flushing after each one. The stream
+// stops at the first error of seq, e.g. a failing database cursor.
+func StreamSeq2[T any](seq iter.Seq2[T, error], format StreamFormat) Result {
+	return StreamFunc(format, func(w *StreamWriter) error {
+		for item, err := range seq {
+			if err != nil {
+				return err
+			}
+
+			if err := w.WriteItem(item); err != nil {
+				return err
+			}
+
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// sendStream writes the streamed result, the status and headers are sent
+// before the first item, so errors after that can only be logged.
+func sendStream(i *BasicInjector, result Result) {
+	if _, exist := i.ResponseHeaders()["Content-Type"]; !exist {
+		i.SetContentType(result.stream.contentType)
+	}
+
+	i.ResponseHeaders().Del("Content-Length")
+	i.w.WriteHeader(result.statusCode)
+
+	w := &StreamWriter{
+		w:           i.w,
+		controller:  http.NewResponseController(i.w),
+		jsonHandler: i.jsonHandler,
+		format:      result.stream.format,
+	}
+
+	if err := w.Flush(); err != nil {
+		i.LogPrintln("err while streaming response, err=", err.Error())
+		return
+	}
+
+	if err := result.stream.write(w); err != nil {
+		i.LogPrintln("err while streaming response, err=", err.Error())
+		return
+	}
+
+	if err := w.end(); err != nil {
+		i.LogPrintln("err while streaming response, err=", err.Error())
+	}
+}
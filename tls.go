@@ -0,0 +1,189 @@
+package flex
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const certReloadCheckInterval = time.Second
+
+// certReloader serves a key pair from disk and loads it again as soon as one of
+// the files is modified, so certificates can be rotated without a restart.
+type certReloader struct {
+	certFile  string
+	keyFile   string
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+	onError   func(err error)
+	*sync.Mutex
+}
+
+func newCertReloader(certFile, keyFile string, onError func(err error)) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		onError:  onError,
+		Mutex:    &sync.Mutex{},
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *certReloader) lastModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+func (r *certReloader) reload() error {
+	modTime, err := r.lastModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if time.Since(r.checkedAt) < certReloadCheckInterval {
+		return r.cert, nil
+	}
+
+	r.checkedAt = time.Now()
+	if modTime, err := r.lastModTime(); err != nil {
+		r.onError(err)
+	} else if !modTime.Equal(r.modTime) {
+		// keep serving the previous pair if the new one is not valid (yet), e.g. half-written
+		if err := r.reload(); err != nil {
+			r.onError(err)
+		}
+	}
+
+	return r.cert, nil
+}
+
+// EnableClientAuth makes the server request client certificates signed by one of
+// the CAs in caFile. Use tls.RequireAndVerifyClientCert for mutual TLS.
+func (s *Server[I]) EnableClientAuth(caFile string, authType tls.ClientAuthType) error {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return errors.New("no valid certificate found in " + caFile)
+	}
+
+	root := s.root()
+	root.clientCAs = pool
+	root.clientAuth = authType
+	return nil
+}
+
+func (s *Server[I]) tlsConfig(certFile, keyFile string) (*tls.Config, error) {
+	var config *tls.Config
+	if c, exist := s.LookupConfig(ConfigTLS); exist {
+		if tc, ok := c.(*tls.Config); !ok {
+			panic("expected a *tls.Config as tls, got " + fmt.Sprint(c))
+		} else {
+			config = tc.Clone()
+		}
+	} else if s.httpServer.TLSConfig != nil {
+		config = s.httpServer.TLSConfig.Clone()
+	} else {
+		config = &tls.Config{}
+	}
+
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+
+	if certFile != "" || keyFile != "" {
+		reloader, err := newCertReloader(certFile, keyFile, func(err error) {
+			s.LogError("could not reload tls certificate, err=", err)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		config.GetCertificate = reloader.GetCertificate
+	} else if len(config.Certificates) == 0 && config.GetCertificate == nil && config.GetConfigForClient == nil {
+		return nil, errors.New("no tls certificate is provided")
+	}
+
+	if s.clientCAs != nil {
+		config.ClientCAs = s.clientCAs
+		config.ClientAuth = s.clientAuth
+	}
+
+	return config, nil
+}
+
+// RunTLS is like Run but serves https. The certificate and key are reloaded
+// whenever they change on disk. If both are empty, certificates of the tls.Config
+// given by the "tls" config key are used.
+func (s *Server[_]) RunTLS(certFile, keyFile string, addr ...string) error {
+	if err := s.setTLSConfig(certFile, keyFile); err != nil {
+		return err
+	}
+
+	listeners, err := s.listen(addr...)
+	if err != nil {
+		return err
+	}
+
+	return s.serve(listeners, true)
+}
+
+// ServeTLS is like Serve but serves https, see RunTLS.
+func (s *Server[_]) ServeTLS(certFile, keyFile string, listeners ...net.Listener) error {
+	if err := s.setTLSConfig(certFile, keyFile); err != nil {
+		return err
+	}
+
+	return s.serve(listeners, true)
+}
+
+func (s *Server[_]) setTLSConfig(certFile, keyFile string) error {
+	s.assertRoot()
+
+	config, err := s.tlsConfig(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	s.httpServer.TLSConfig = config
+	return nil
+}
@@ -0,0 +1,67 @@
+package flex
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Handle registers a handler whose request body is decoded as Body before the
+// call and whose response is sent as Resp, e.g.
+// flex.POST(s, "/users", func(i *BasicInjector, u User) (User, error) {...}).
+// Use NoBody as Body for a route with no body. Errors are converted as for
+// handlers of the form func(I) (any, error), see MapError, and a nil pointer
+// response is sent as 204. The body and response types are recorded on the
+// route for OpenAPI.
+func Handle[I Injector, Body, Resp any](s *Server[I], method, path string, handler func(i I, body Body) (Resp, error)) *RouteInfo {
+	if handler == nil {
+		panic("handler can not be nil")
+	}
+
+	if kind := reflect.TypeFor[Body]().Kind(); kind == reflect.Interface || kind == reflect.Pointer {
+		panic("body type must be a concrete non-pointer type, got " + reflect.TypeFor[Body]().String())
+	}
+
+	route := s.Handle(method, path, func(i I) (any, error) {
+		var body Body
+		if _, noBody := any(body).(NoBody); !noBody {
+			requestBody := i.RequestBody()
+			var ok bool
+			if body, ok = requestBody.(Body); !ok {
+				panic(i.WrapInternalErr(fmt.Sprintf("expected a request body of type %T, got %T", body, requestBody)))
+			}
+		}
+
+		return handler(i, body)
+	}, *new(Body))
+
+	// a Result, like an interface, is only known at runtime
+	if respType := reflect.TypeFor[Resp](); respType.Kind() != reflect.Interface && respType != reflect.TypeFor[Result]() {
+		route.WithResponse(http.StatusOK, *new(Resp), "")
+		if respType.Kind() == reflect.Pointer {
+			route.WithResponse(http.StatusNoContent, nil, "")
+		}
+	}
+
+	return route
+}
+
+func GET[I Injector, Body, Resp any](s *Server[I], path string, handler func(i I, body Body) (Resp, error)) *RouteInfo {
+	return Handle(s, http.MethodGet, path, handler)
+}
+
+func POST[I Injector, Body, Resp any](s *Server[I], path string, handler func(i I, body Body) (Resp, error)) *RouteInfo {
+	return Handle(s, http.MethodPost, path, handler)
+}
+
+func PUT[I Injector, Body, Resp any](s *Server[I], path string, handler func(i I, body Body) (Resp, error)) *RouteInfo {
+	return Handle(s, http.MethodPut, path, handler)
+}
+
+func PATCH[I Injector, Body, Resp any](s *Server[I], path string, handler func(i I, body Body) (Resp, error)) *RouteInfo {
+	return Handle(s, http.MethodPatch, path, handler)
+}
+
+func DELETE[I Injector, Body, Resp any](s *Server[I], path string, handler func(i I, body Body) (Resp, error)) *RouteInfo {
+	return Handle(s, http.MethodDelete, path, handler)
+}
@@ -0,0 +1,202 @@
+package util
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// SetFromString parses raw according to the type of v and stores the result in
+// v, which must be settable. Besides the basic kinds, time.Duration, time.Time
+// (RFC 3339), pointers, encoding.TextUnmarshaler implementations and slices (as
+// comma separated values) are supported.
+func SetFromString(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return SetFromString(v.Elem(), raw)
+	}
+
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return err
+		}
+
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	case reflect.Slice:
+		if raw == "" {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+			return nil
+		}
+
+		return SetFromStrings(v, strings.Split(raw, ","))
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("unsupported type: %s", v.Type())
+		}
+
+		v.Set(reflect.ValueOf(raw))
+	default:
+		return fmt.Errorf("unsupported type: %s", v.Type())
+	}
+
+	return nil
+}
+
+// SetFromStrings is like SetFromString but accepts multiple values, which are
+// stored one per element if v is a slice. Otherwise, the first value is used.
+func SetFromStrings(v reflect.Value, raws []string) error {
+	if len(raws) == 0 {
+		return nil
+	}
+
+	if v.Kind() != reflect.Slice || reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return SetFromString(v, raws[0])
+	}
+
+	slice := reflect.MakeSlice(v.Type(), len(raws), len(raws))
+	for index, raw := range raws {
+		if err := SetFromString(slice.Index(index), strings.TrimSpace(raw)); err != nil {
+			return fmt.Errorf("index %d: %w", index, err)
+		}
+	}
+
+	v.Set(slice)
+	return nil
+}
+
+// SetFromAny stores value in v, converting it if its type is not assignable to
+// v. Strings are parsed with SetFromString. Numbers are rejected for
+// time.Duration, as their unit is ambiguous.
+func SetFromAny(v reflect.Value, value any) error {
+	if value == nil {
+		return nil
+	}
+
+	val := reflect.ValueOf(value)
+	if val.Type().AssignableTo(v.Type()) {
+		v.Set(val)
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return SetFromAny(v.Elem(), value)
+	}
+
+	if str, ok := value.(string); ok {
+		return SetFromString(v, str)
+	}
+
+	// a bare number, e.g. 30 of a json or yaml config, would be 30ns
+	if v.Type() == durationType && isNumberKind(val.Kind()) {
+		return fmt.Errorf("a number is not a duration, use a string with a unit like \"%vs\" instead of %v", value, value)
+	}
+
+	if isNumberKind(val.Kind()) && isNumberKind(v.Kind()) {
+		// -1 converts to the max of an unsigned type and back to -1
+		if v.CanUint() && (val.CanInt() && val.Int() < 0 || val.CanFloat() && val.Float() < 0) {
+			return fmt.Errorf("%v is negative, can not be stored in %s", value, v.Type())
+		}
+
+		converted := val.Convert(v.Type())
+		if !converted.Convert(val.Type()).Equal(val) {
+			return fmt.Errorf("%v overflows %s", value, v.Type())
+		}
+
+		v.Set(converted)
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice && (val.Kind() == reflect.Slice || val.Kind() == reflect.Array) {
+		slice := reflect.MakeSlice(v.Type(), val.Len(), val.Len())
+		for index := 0; index < val.Len(); index++ {
+			if err := SetFromAny(slice.Index(index), val.Index(index).Interface()); err != nil {
+				return fmt.Errorf("index %d: %w", index, err)
+			}
+		}
+
+		v.Set(slice)
+		return nil
+	}
+
+	if val.Type().ConvertibleTo(v.Type()) && val.Kind() == v.Kind() {
+		v.Set(val.Convert(v.Type()))
+		return nil
+	}
+
+	return fmt.Errorf("can not convert %T to %s", value, v.Type())
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
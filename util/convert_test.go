@@ -0,0 +1,50 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetFromAnyNumbers(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   any
+		value    any
+		expected any
+		err      bool
+	}{
+		{"int to uint", uint(0), 8080, uint(8080), false},
+		{"float to uint16", uint16(0), 8080.0, uint16(8080), false},
+		{"float to int", 0, 30.0, 30, false},
+		{"negative int to uint64", uint64(0), -1, nil, true},
+		{"negative float to uint", uint(0), -1.0, nil, true},
+		{"negative int to int", 0, -1, -1, false},
+		{"overflow of uint8", uint8(0), 256, nil, true},
+		{"fraction to int", 0, 1.5, nil, true},
+		{"number to duration", time.Duration(0), 30, nil, true},
+		{"string to duration", time.Duration(0), "30s", 30 * time.Second, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := reflect.New(reflect.TypeOf(test.target)).Elem()
+			err := SetFromAny(v, test.value)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", v.Interface())
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if v.Interface() != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, v.Interface())
+			}
+		})
+	}
+}
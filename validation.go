@@ -0,0 +1,96 @@
+package flex
+
+import (
+	"github.com/amirdlt/ffvm"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// ffvmTypes caches whether a body type has ffvm tags.
+var ffvmTypes sync.Map
+
+// SetBodyValidation enables or disables the ffvm validation of the request
+// bodies of s and its groups which do not set their own. It is enabled by
+// default, an invalid body is rejected with 422 listing its issues.
+func (s *Server[I]) SetBodyValidation(enabled bool) *Server[I] {
+	s.bodyValidation = &enabled
+	return s
+}
+
+func (s *Server[I]) lookupBodyValidation() bool {
+	for ; s != nil; s = s.parent {
+		if s.bodyValidation != nil {
+			return *s.bodyValidation
+		}
+	}
+
+	return true
+}
+
+// WithValidation enables or disables the ffvm validation of the request body
+// of the route, over the setting of its server.
+func (r *RouteInfo) WithValidation(enabled bool) *RouteInfo {
+	r.Validation = &enabled
+	return r
+}
+
+// validatesBody reports whether the body is validated, which needs ffvm tags
+// in its type.
+func (s *BasicInjector) validatesBody() bool {
+	enabled := s.bodyValidation
+	if s.route != nil && s.route.Validation != nil {
+		enabled = *s.route.Validation
+	}
+
+	return enabled && hasFFVMTags(s.bodyType)
+}
+
+// validateBody panics with 422 if the decoded body is invalid.
+func (s *BasicInjector) validateBody(body reflect.Value) {
+	if !s.validatesBody() {
+		return
+	}
+
+	if issues := ffvm.Validate(body.Interface()); len(issues) != 0 {
+		panic(s.WrapUnprocessableEntityErr(issues))
+	}
+}
+
+func (s *BasicInjector) WrapUnprocessableEntityErr(err any) Result {
+	return s.WrapJsonErr(err, s.defaultErrorCodes[http.StatusUnprocessableEntity], http.StatusUnprocessableEntity)
+}
+
+func hasFFVMTags(t reflect.Type) bool {
+	if cached, exist := ffvmTypes.Load(t); exist {
+		return cached.(bool)
+	}
+
+	has := hasFFVMTagsOf(t, map[reflect.Type]bool{})
+	ffvmTypes.Store(t, has)
+	return has
+}
+
+func hasFFVMTagsOf(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+
+	seen[t] = true
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+		if _, ok := field.Tag.Lookup("ffvm"); ok {
+			return true
+		}
+
+		if hasFFVMTagsOf(field.Type, seen) {
+			return true
+		}
+	}
+
+	return false
+}
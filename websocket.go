@@ -0,0 +1,305 @@
+package flex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	WebSocketTextMessage   = websocket.TextMessage
+	WebSocketBinaryMessage = websocket.BinaryMessage
+)
+
+const (
+	defaultWebSocketReadLimit    = 1 << 20
+	defaultWebSocketPingInterval = 30 * time.Second
+	defaultWebSocketWriteTimeout = 10 * time.Second
+)
+
+// WebSocketOptions configures the connections accepted by Server.WebSocket,
+// zero values are replaced by the defaults.
+type WebSocketOptions struct {
+	// ReadLimit is the max size of a received message in bytes, 1MiB by
+	// default. Larger messages close the connection with 1009.
+	ReadLimit int64
+	// PingInterval is the interval of keepalive pings, 30s by default, and
+	// negative disables them.
+	PingInterval time.Duration
+	// PongTimeout is how long to wait for a pong before the connection is
+	// considered dead, twice the PingInterval by default. Pongs are processed
+	// while reading, so the handler must keep reading for keepalive to work.
+	PongTimeout time.Duration
+	// WriteTimeout bounds each write, 10s by default.
+	WriteTimeout time.Duration
+	// CheckOrigin accepts only same origin requests by default.
+	CheckOrigin       func(r *http.Request) bool
+	Subprotocols      []string
+	ReadBufferSize    int
+	WriteBufferSize   int
+	EnableCompression bool
+}
+
+// WebSocketConn is an upgraded connection, reads must be done from one
+// goroutine while writes are safe for concurrent use.
+type WebSocketConn struct {
+	conn        *websocket.Conn
+	jsonHandler JsonHandler
+	options     WebSocketOptions
+	ctx         context.Context
+	cancel      context.CancelFunc
+	writeMutex  *sync.Mutex
+}
+
+// WebSocket registers a GET route at path which upgrades the connection and
+// calls handler with it. The wrappers of the server run before the upgrade, so
+// e.g. auth wrappers can reject the request with a normal response. The
+// connection is closed when handler returns, with 1000 if it returns nil and
+// 1011 otherwise.
+func (s *Server[I]) WebSocket(path string, handler func(i I, conn *WebSocketConn) error, options ...WebSocketOptions) *RouteInfo {
+	if handler == nil {
+		panic("websocket handler can not be nil")
+	}
+
+	var opts WebSocketOptions
+	switch len(options) {
+	case 0:
+	case 1:
+		opts = options[0]
+	default:
+		panic("options must be one arg at max")
+	}
+
+	if opts.ReadLimit == 0 {
+		opts.ReadLimit = defaultWebSocketReadLimit
+	}
+
+	if opts.PingInterval == 0 {
+		opts.PingInterval = defaultWebSocketPingInterval
+	}
+
+	if opts.PongTimeout == 0 {
+		opts.PongTimeout = 2 * opts.PingInterval
+	}
+
+	if opts.WriteTimeout == 0 {
+		opts.WriteTimeout = defaultWebSocketWriteTimeout
+	}
+
+	upgrader := &websocket.Upgrader{
+		ReadBufferSize:    opts.ReadBufferSize,
+		WriteBufferSize:   opts.WriteBufferSize,
+		Subprotocols:      opts.Subprotocols,
+		CheckOrigin:       opts.CheckOrigin,
+		EnableCompression: opts.EnableCompression,
+	}
+
+	return s.GET(path, func(i I) Result {
+		// the upgrader writes the error response itself on failure
+		conn, err := upgrader.Upgrade(i.response(), i.request(), nil)
+		if err != nil {
+			return Result{terminate: true}
+		}
+
+		ctx, cancel := context.WithCancel(context.WithoutCancel(i.request().Context()))
+		wsConn := &WebSocketConn{
+			conn:        conn,
+			jsonHandler: s.jsonHandler,
+			options:     opts,
+			ctx:         ctx,
+			cancel:      cancel,
+			writeMutex:  &sync.Mutex{},
+		}
+
+		webSockets := s.root().webSockets
+		webSockets.add(wsConn)
+		defer webSockets.remove(wsConn)
+
+		if err := wsConn.serve(func() error { return handler(i, wsConn) }); err != nil {
+			s.LogErrorf("websocket handler failed, path=%s, err=%v", path, err)
+		}
+
+		return Result{terminate: true}
+	}, noBody).WithMetadata("websocket", true)
+}
+
+// webSocketConns are the live connections of a server, closed with 1001 on
+// shutdown.
+type webSocketConns struct {
+	conns map[*WebSocketConn]struct{}
+	*sync.Mutex
+}
+
+func newWebSocketConns() *webSocketConns {
+	return &webSocketConns{conns: map[*WebSocketConn]struct{}{}, Mutex: &sync.Mutex{}}
+}
+
+func (w *webSocketConns) add(conn *WebSocketConn) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.conns[conn] = struct{}{}
+}
+
+func (w *webSocketConns) remove(conn *WebSocketConn) {
+	w.Lock()
+	defer w.Unlock()
+
+	delete(w.conns, conn)
+}
+
+func (w *webSocketConns) closeAll() {
+	w.Lock()
+	conns := make([]*WebSocketConn, 0, len(w.conns))
+	for conn := range w.conns {
+		conns = append(conns, conn)
+	}
+	w.Unlock()
+
+	for _, conn := range conns {
+		go func() {
+			_ = conn.Close(websocket.CloseGoingAway, "server is shutting down")
+		}()
+	}
+}
+
+// serve runs handler and closes the connection, a panic of handler is
+// returned as error since the connection is hijacked and no response can be
+// sent anymore.
+func (c *WebSocketConn) serve(handler func() error) (err error) {
+	defer c.conn.Close()
+	defer c.cancel()
+
+	c.conn.SetReadLimit(c.options.ReadLimit)
+	if c.options.PingInterval > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.options.PongTimeout))
+		c.conn.SetPongHandler(func(string) error {
+			return c.conn.SetReadDeadline(time.Now().Add(c.options.PongTimeout))
+		})
+
+		go c.keepalive()
+	}
+
+	defer func() {
+		if catch := recover(); catch != nil {
+			err = fmt.Errorf("panic: %v", catch)
+		}
+
+		switch {
+		case err == nil:
+			_ = c.Close(websocket.CloseNormalClosure, "")
+		case IsWebSocketClosed(err):
+			err = nil
+		default:
+			_ = c.Close(websocket.CloseInternalServerErr, "internal error")
+		}
+	}()
+
+	return handler()
+}
+
+func (c *WebSocketConn) keepalive() {
+	ticker := time.NewTicker(c.options.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.options.WriteTimeout)); err != nil {
+				c.cancel()
+				return
+			}
+		}
+	}
+}
+
+// ReadMessage reads the next text or binary message, control messages are
+// handled while reading. The context of the connection is cancelled when a
+// read fails.
+func (c *WebSocketConn) ReadMessage() (int, []byte, error) {
+	messageType, data, err := c.conn.ReadMessage()
+	if err != nil {
+		c.cancel()
+	}
+
+	return messageType, data, err
+}
+
+// ReadJSON reads the next message and unmarshals it into v.
+func (c *WebSocketConn) ReadJSON(v any) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+
+	return c.jsonHandler.Unmarshal(data, v)
+}
+
+func (c *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.options.WriteTimeout)); err != nil {
+		return err
+	}
+
+	return c.conn.WriteMessage(messageType, data)
+}
+
+func (c *WebSocketConn) WriteText(text string) error {
+	return c.WriteMessage(websocket.TextMessage, []byte(text))
+}
+
+// WriteJSON marshals v and writes it as a text message.
+func (c *WebSocketConn) WriteJSON(v any) error {
+	data, err := c.jsonHandler.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteMessage(websocket.TextMessage, data)
+}
+
+// Close sends a close message with code and reason and closes the connection.
+func (c *WebSocketConn) Close(code int, reason string) error {
+	defer c.cancel()
+
+	err := c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(c.options.WriteTimeout))
+	if errors.Is(err, websocket.ErrCloseSent) {
+		err = nil
+	}
+
+	return errors.Join(err, c.conn.Close())
+}
+
+// Context is done when the connection is closed or a read or ping fails.
+func (c *WebSocketConn) Context() context.Context {
+	return c.ctx
+}
+
+func (c *WebSocketConn) Subprotocol() string {
+	return c.conn.Subprotocol()
+}
+
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Conn returns the underlying gorilla connection.
+func (c *WebSocketConn) Conn() *websocket.Conn {
+	return c.conn
+}
+
+// IsWebSocketClosed reports whether err is caused by the peer closing the
+// connection or the connection being closed.
+func IsWebSocketClosed(err error) bool {
+	var closeErr *websocket.CloseError
+	return errors.As(err, &closeErr) || errors.Is(err, websocket.ErrCloseSent) || errors.Is(err, net.ErrClosed)
+}